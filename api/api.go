@@ -1,12 +1,10 @@
 package api
 
 import (
-	"context"
-	"errors"
-	"fmt"
+	"log/slog"
 	"net/http"
-	"strconv"
-	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/felipe88alves/sortKeyHttpServer/types"
 )
@@ -16,95 +14,105 @@ const (
 )
 
 type apiServer struct {
-	svc service
+	svc     service
+	metrics *serverMetrics
+	mux     *http.ServeMux
+
+	// snapshots is the cache behind svc, nil if svc keeps no snapshots
+	snapshots snapshotService
+
+	maxSnapshotAge time.Duration
+	config         ServerConfig
+	compression    CompressionConfig
+
+	// draining is set on shutdown, to report not ready while in-flight requests complete
+	draining atomic.Bool
 }
 
-func NewApiServer(svc service) *apiServer {
-	return &apiServer{
-		svc: svc,
+func NewApiServer(svc service, opts ...ServerOption) *apiServer {
+	s := &apiServer{
+		svc:         svc,
+		snapshots:   findSnapshotService(svc),
+		metrics:     defaultMetrics,
+		compression: DefaultCompressionConfig(),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.snapshots == nil {
+		slog.Warn("The service keeps no snapshots. Readiness will report not ready")
+	}
+	s.mux = s.newRouter()
+	return s
 }
 
-func (s *apiServer) Start(listenAddr string) error {
-	http.HandleFunc("/", middlewareHandler(s.handleRawStats))
-	http.HandleFunc(fmt.Sprintf("/%s/", sortkeyPath), middlewareHandler(s.handleSortKey))
-	return http.ListenAndServe(listenAddr, nil)
+// Start serves in the background and returns the server, to be passed to Shutdown.
+// Errors occurring after the server started listening are sent on the returned channel.
+func (s *apiServer) Start(listenAddr string) (*http.Server, <-chan error, error) {
+	srv := s.newHttpServer(listenAddr, s)
+	serveErr, err := serve(srv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return srv, serveErr, nil
 }
 
 func (s *apiServer) handleRawStats(w http.ResponseWriter, r *http.Request) *handlerResponse {
-	if r.URL.Path != "/" {
-		// Returning nil, since the "/" pattern will always be called
-		// A proper Mux would allow for the appropriate BadRequest Status Code Response
-		return nil
-	}
-	urlStats, err := s.svc.getUrlStatsData((context.Background()))
+	urlStats, err := s.svc.getUrlStatsData(r.Context())
 	if err != nil {
-		if errStatusCode, errStrconv := strconv.Atoi(err.Error()); errStrconv != nil {
-			return &handlerResponse{Err: err, StatusCode: http.StatusInternalServerError}
-		} else {
-			return &handlerResponse{Err: err, StatusCode: errStatusCode}
-		}
+		return newErrorHandlerResponse(err)
 	}
-
-	switch r.Method {
-	case http.MethodGet:
-		jsonReturnMsg := types.ResponseUrlStats{
-			SortedUrlStats: &urlStats.Data,
-			Count:          len(urlStats.Data),
+	if getStrictSourcesValue(r.URL.Query()) {
+		if err := newPartialFailureError(urlStats.Sources); err != nil {
+			return newErrorHandlerResponse(err)
 		}
-		return &handlerResponse{resp: &jsonReturnMsg, StatusCode: http.StatusOK}
+	}
 
-	default:
-		return &handlerResponse{
-			Err:        errors.New(http.StatusText(http.StatusMethodNotAllowed)),
-			StatusCode: http.StatusMethodNotAllowed}
+	jsonReturnMsg := types.ResponseUrlStats{
+		SortedUrlStats: &urlStats.Data,
+		Count:          len(urlStats.Data),
+		Total:          len(urlStats.Data),
+		Snapshot:       urlStats.Snapshot,
+		Sources:        urlStats.Sources,
 	}
+	return &handlerResponse{resp: &jsonReturnMsg, StatusCode: http.StatusOK}
 }
 
 func (s *apiServer) handleSortKey(w http.ResponseWriter, r *http.Request) *handlerResponse {
-	urlPathSegments := strings.Split(r.URL.Path, fmt.Sprintf("%s/", sortkeyPath))
-	if len(urlPathSegments) == 1 || len(urlPathSegments) > 2 {
-		return &handlerResponse{
-			Err:        errors.New(http.StatusText(http.StatusBadRequest)),
-			StatusCode: http.StatusBadRequest}
+	sortBy := r.PathValue(sortKeyPathValue)
+	sortKeys, err := parseSortKeys(sortBy, getStrictValue(r.URL.Query()))
+	if err != nil {
+		return newErrorHandlerResponse(err)
 	}
-	urlPathSegments = strings.Split(urlPathSegments[1], "/")
-	if len(urlPathSegments) != 1 || urlPathSegments[0] == "" {
-		return &handlerResponse{
-			Err:        errors.New(http.StatusText(http.StatusBadRequest)),
-			StatusCode: http.StatusBadRequest}
+	filter, err := parseFilters(r.URL.Query())
+	if err != nil {
+		return newErrorHandlerResponse(err)
 	}
 
-	urlStats, err := s.svc.getUrlStatsData((context.Background()))
+	page, err := parsePageRequest(sortBy, r.URL.Query())
 	if err != nil {
-		if errStatusCode, errStrconv := strconv.Atoi(err.Error()); errStrconv != nil {
-			return &handlerResponse{Err: err, StatusCode: http.StatusInternalServerError}
-		} else {
-			return &handlerResponse{Err: err, StatusCode: errStatusCode}
-		}
+		return newErrorHandlerResponse(err)
 	}
 
-	switch r.Method {
-	case http.MethodGet:
-		sortOption := urlPathSegments[0]
-		urlStatResponse, err := mergeSort(&urlStats.Data, sortOption)
-		if err != nil {
-			return &handlerResponse{Err: err, StatusCode: http.StatusInternalServerError}
+	urlStats, err := s.getUrlStatsDataForPage(r.Context(), page)
+	if err != nil {
+		return newErrorHandlerResponse(err)
+	}
+	if getStrictSourcesValue(r.URL.Query()) {
+		if err := newPartialFailureError(urlStats.Sources); err != nil {
+			return newErrorHandlerResponse(err)
 		}
+	}
 
-		urlStatResponse, err = limitReponse(urlStatResponse, r.URL.Query())
-		if err != nil {
-			return &handlerResponse{Err: err, StatusCode: http.StatusInternalServerError}
-		}
+	urlStatResponse, total, err := sortUrlStats(urlStats, sortKeys, filter, page)
+	if err != nil {
+		return newErrorHandlerResponse(err)
+	}
 
-		jsonReturnMsg := types.ResponseUrlStats{
-			SortedUrlStats: urlStatResponse,
-			Count:          len(*urlStatResponse),
-		}
-		return &handlerResponse{resp: &jsonReturnMsg, StatusCode: http.StatusOK}
-	default:
-		return &handlerResponse{
-			Err:        errors.New(http.StatusText(http.StatusMethodNotAllowed)),
-			StatusCode: http.StatusMethodNotAllowed}
+	jsonReturnMsg, err := newPagedResponse(urlStatResponse, total, page, urlStats.Snapshot)
+	if err != nil {
+		return newErrorHandlerResponse(err)
 	}
+	jsonReturnMsg.Sources = urlStats.Sources
+	return &handlerResponse{resp: jsonReturnMsg, StatusCode: http.StatusOK}
 }
@@ -6,13 +6,14 @@ import (
 	"math"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
 	"runtime"
 	"strconv"
+	"strings"
 	"testing"
-	"time"
 
 	"github.com/felipe88alves/sortKeyHttpServer/types"
 	"github.com/felipe88alves/sortKeyHttpServer/utils"
@@ -31,8 +32,14 @@ func init() {
 	)
 
 	// Overwritting global params to reduce test time
-	retryAttempts = 1
-	backoffPeriods = []time.Duration{0}
+	defaultRetryPolicy = RetryPolicy{MaxAttempts: 1}
+}
+
+// newSortKeyRequest sets the sort key path value, as the router would
+func newSortKeyRequest(target string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.SetPathValue(sortKeyPathValue, strings.TrimPrefix(req.URL.Path, "/"+sortkeyPath+"/"))
+	return req
 }
 
 func TestHandleSortKey_sortOption(t *testing.T) {
@@ -40,8 +47,9 @@ func TestHandleSortKey_sortOption(t *testing.T) {
 		unsupported = "unsupported"
 	)
 	var (
-		responseSortedRelevancescore = `{"data":[{"url":"www.example.com/abc5","views":5000,"relevanceScore":0.1},{"url":"www.example.com/abc3","views":3000,"relevanceScore":0.2},{"url":"www.example.com/abc4","views":4000,"relevanceScore":0.3},{"url":"www.example.com/abc2","views":2000,"relevanceScore":0.4},{"url":"www.example.com/abc1","views":1000,"relevanceScore":0.5}],"count":5}`
-		responseSortedViews          = `{"data":[{"url":"www.example.com/abc1","views":1000,"relevanceScore":0.5},{"url":"www.example.com/abc2","views":2000,"relevanceScore":0.4},{"url":"www.example.com/abc3","views":3000,"relevanceScore":0.2},{"url":"www.example.com/abc4","views":4000,"relevanceScore":0.3},{"url":"www.example.com/abc5","views":5000,"relevanceScore":0.1}],"count":5}`
+		responseSortedRelevancescore = `{"data":[{"url":"www.example.com/abc5","normalizedUrl":"https://www.example.com/abc5","views":5000,"relevanceScore":0.1},{"url":"www.example.com/abc3","normalizedUrl":"https://www.example.com/abc3","views":3000,"relevanceScore":0.2},{"url":"www.example.com/abc4","normalizedUrl":"https://www.example.com/abc4","views":4000,"relevanceScore":0.3},{"url":"www.example.com/abc2","normalizedUrl":"https://www.example.com/abc2","views":2000,"relevanceScore":0.4},{"url":"www.example.com/abc1","normalizedUrl":"https://www.example.com/abc1","views":1000,"relevanceScore":0.5}],"count":5,"total":5}`
+		responseSortedViews          = `{"data":[{"url":"www.example.com/abc1","normalizedUrl":"https://www.example.com/abc1","views":1000,"relevanceScore":0.5},{"url":"www.example.com/abc2","normalizedUrl":"https://www.example.com/abc2","views":2000,"relevanceScore":0.4},{"url":"www.example.com/abc3","normalizedUrl":"https://www.example.com/abc3","views":3000,"relevanceScore":0.2},{"url":"www.example.com/abc4","normalizedUrl":"https://www.example.com/abc4","views":4000,"relevanceScore":0.3},{"url":"www.example.com/abc5","normalizedUrl":"https://www.example.com/abc5","views":5000,"relevanceScore":0.1}],"count":5,"total":5}`
+		responseSortedViewsDesc      = `{"data":[{"url":"www.example.com/abc5","normalizedUrl":"https://www.example.com/abc5","views":5000,"relevanceScore":0.1},{"url":"www.example.com/abc4","normalizedUrl":"https://www.example.com/abc4","views":4000,"relevanceScore":0.3},{"url":"www.example.com/abc3","normalizedUrl":"https://www.example.com/abc3","views":3000,"relevanceScore":0.2},{"url":"www.example.com/abc2","normalizedUrl":"https://www.example.com/abc2","views":2000,"relevanceScore":0.4},{"url":"www.example.com/abc1","normalizedUrl":"https://www.example.com/abc1","views":1000,"relevanceScore":0.5}],"count":5,"total":5}`
 
 		testUrlDataSourceFile = urlDataSourceFile
 		testFolderDataSource  = "testHandleSortKey"
@@ -70,9 +78,35 @@ func TestHandleSortKey_sortOption(t *testing.T) {
 			expectedResponse:   responseSortedViews,
 		},
 		{
-			name:               "SortOption: unsupported - defaults to relevanceScore",
+			name:               "SortOption: Views descending",
+			inputSortOption:    viewsOption + sortDirectionSeparator + sortDirectionDesc,
+			inputTestFileDir:   successDir,
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   responseSortedViewsDesc,
+		},
+		{
+			name:               "SortOption: multiple keys",
+			inputSortOption:    "relevanceScore:asc,views:desc,url",
+			inputTestFileDir:   successDir,
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   responseSortedRelevancescore,
+		},
+		{
+			name:               "SortOption: unsupported - strict validation",
 			inputSortOption:    unsupported,
 			inputTestFileDir:   successDir,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "SortOption: unsupported direction - strict validation",
+			inputSortOption:    viewsOption + sortDirectionSeparator + unsupported,
+			inputTestFileDir:   successDir,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "SortOption: unsupported - strict=false defaults to relevanceScore",
+			inputSortOption:    unsupported + "?" + strictFilterOption + "=false",
+			inputTestFileDir:   successDir,
 			expectedStatusCode: http.StatusOK,
 			expectedResponse:   responseSortedRelevancescore,
 		},
@@ -82,9 +116,7 @@ func TestHandleSortKey_sortOption(t *testing.T) {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
-			req := httptest.NewRequest(http.MethodGet,
-				fmt.Sprintf("/%s/%s", sortkeyPath, tc.inputSortOption),
-				nil)
+			req := newSortKeyRequest(fmt.Sprintf("/%s/%s", sortkeyPath, tc.inputSortOption))
 			rec := httptest.NewRecorder()
 
 			relPath := filepath.Join(apiTestRelativePath, testFolderDataSource, tc.inputTestFileDir)
@@ -101,6 +133,9 @@ func TestHandleSortKey_sortOption(t *testing.T) {
 				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
 					tc.name, tc.expectedStatusCode, handlerResp.StatusCode)
 			}
+			if tc.expectedResponse == "" {
+				return
+			}
 
 			expectedResponseUrlStats := new(types.ResponseUrlStats)
 			if err := json.Unmarshal([]byte(tc.expectedResponse), &expectedResponseUrlStats); err != nil {
@@ -108,6 +143,9 @@ func TestHandleSortKey_sortOption(t *testing.T) {
 					tc.name, err.Error())
 			}
 
+			// Sources are covered by TestGetUrlStatsDataFromFile_Validation
+			expectedResponseUrlStats.Sources = handlerResp.resp.Sources
+
 			assert := reflect.DeepEqual(expectedResponseUrlStats, handlerResp.resp)
 			if !assert {
 				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
@@ -145,14 +183,21 @@ func TestHandleSortKey_sortKeyPath(t *testing.T) {
 			name:               "Invalid sortKeyPath: No Sort Option declared",
 			inputSortKeyPath:   sortkeyPath,
 			inputTestFileDir:   successDir,
-			expectedStatusCode: http.StatusBadRequest,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Invalid sortKeyPath: Empty Sort Option",
+			inputSortKeyPath:   sortkeyPath,
+			inputSortOption:    "/",
+			inputTestFileDir:   successDir,
+			expectedStatusCode: http.StatusNotFound,
 		},
 		{
 			name:               "Invalid sortKeyPath: Invalid Sort Option",
 			inputSortKeyPath:   sortkeyPath + "/" + unsupportedKeyPath,
 			inputSortOption:    "/" + relevancescoreOption,
 			inputTestFileDir:   successDir,
-			expectedStatusCode: http.StatusBadRequest,
+			expectedStatusCode: http.StatusNotFound,
 		},
 	}
 
@@ -173,11 +218,11 @@ func TestHandleSortKey_sortKeyPath(t *testing.T) {
 			}
 			apiServer := NewApiServer(svc)
 
-			handlerResp := apiServer.handleSortKey(rec, req)
+			apiServer.ServeHTTP(rec, req)
 
-			if handlerResp.StatusCode != tc.expectedStatusCode {
+			if rec.Code != tc.expectedStatusCode {
 				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
-					tc.name, tc.expectedStatusCode, handlerResp.StatusCode)
+					tc.name, tc.expectedStatusCode, rec.Code)
 			}
 		})
 	}
@@ -193,6 +238,7 @@ func TestHandleSortKey_httpMethod(t *testing.T) {
 		name                      string
 		httpMethod                string
 		expectedStatusCode        int
+		expectedAllow             string
 		expectedUrlsReturnedCount int
 		expectedCount             int
 	}{
@@ -207,11 +253,13 @@ func TestHandleSortKey_httpMethod(t *testing.T) {
 			name:               "httpMethod: PUT",
 			httpMethod:         http.MethodPut,
 			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedAllow:      "GET, HEAD",
 		},
 		{
 			name:               "httpMethod: unsupported",
 			httpMethod:         unsupportedHttMethod,
 			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedAllow:      "GET, HEAD",
 		},
 	}
 
@@ -231,25 +279,29 @@ func TestHandleSortKey_httpMethod(t *testing.T) {
 			}
 			apiServer := NewApiServer(svc)
 
-			handlerResp := apiServer.handleSortKey(rec, req)
+			apiServer.ServeHTTP(rec, req)
 
-			if handlerResp.StatusCode != tc.expectedStatusCode {
+			if rec.Code != tc.expectedStatusCode {
 				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
-					tc.name, tc.expectedStatusCode, handlerResp.StatusCode)
+					tc.name, tc.expectedStatusCode, rec.Code)
 			}
 			if tc.expectedStatusCode >= 200 && tc.expectedStatusCode < 300 {
-				if len(*handlerResp.resp.SortedUrlStats) != tc.expectedUrlsReturnedCount {
+				var resp types.ResponseUrlStats
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("Test Failed: %v Failed to decode response. Error: %v", tc.name, err)
+				}
+				if len(*resp.SortedUrlStats) != tc.expectedUrlsReturnedCount {
 					t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
-						tc.name, tc.expectedUrlsReturnedCount, len(*handlerResp.resp.SortedUrlStats))
+						tc.name, tc.expectedUrlsReturnedCount, len(*resp.SortedUrlStats))
 				}
-				if handlerResp.resp.Count != tc.expectedCount {
+				if resp.Count != tc.expectedCount {
 					t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
-						tc.name, tc.expectedCount, handlerResp.resp.Count)
+						tc.name, tc.expectedCount, resp.Count)
 				}
 			} else {
-				if handlerResp.Error() != http.StatusText(tc.expectedStatusCode) {
+				if allow := rec.Header().Get("Allow"); allow != tc.expectedAllow {
 					t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
-						tc.name, tc.expectedUrlsReturnedCount, *handlerResp.resp.SortedUrlStats)
+						tc.name, tc.expectedAllow, allow)
 				}
 			}
 		})
@@ -309,9 +361,7 @@ func TestHandleSortKey_limitFilter(t *testing.T) {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
-			req := httptest.NewRequest(http.MethodGet,
-				fmt.Sprintf("/%s/%s?%s=%s", sortkeyPath, relevancescoreOption, limitFilterOption, tc.limitFilter),
-				nil)
+			req := newSortKeyRequest(fmt.Sprintf("/%s/%s?%s=%s", sortkeyPath, relevancescoreOption, limitFilterOption, tc.limitFilter))
 			rec := httptest.NewRecorder()
 
 			svc, err := NewUrlStatDataService(testUrlDataSourceFile, testFileDataSource)
@@ -387,7 +437,7 @@ func TestWriteJson(t *testing.T) {
 
 func TestHandleRawStats(t *testing.T) {
 	const (
-		responseUnsorted string = `{"data":[{"url":"www.example.com/abc1","views":1000,"relevanceScore":0.5},{"url":"www.example.com/abc5","views":5000,"relevanceScore":0.1},{"url":"www.example.com/abc3","views":3000,"relevanceScore":0.2},{"url":"www.example.com/abc2","views":2000,"relevanceScore":0.4},{"url":"www.example.com/abc4","views":4000,"relevanceScore":0.3}],"count":5}`
+		responseUnsorted string = `{"data":[{"url":"www.example.com/abc1","normalizedUrl":"https://www.example.com/abc1","views":1000,"relevanceScore":0.5},{"url":"www.example.com/abc5","normalizedUrl":"https://www.example.com/abc5","views":5000,"relevanceScore":0.1},{"url":"www.example.com/abc3","normalizedUrl":"https://www.example.com/abc3","views":3000,"relevanceScore":0.2},{"url":"www.example.com/abc2","normalizedUrl":"https://www.example.com/abc2","views":2000,"relevanceScore":0.4},{"url":"www.example.com/abc4","normalizedUrl":"https://www.example.com/abc4","views":4000,"relevanceScore":0.3}],"count":5,"total":5}`
 
 		testFolderDataSource = "testHandleRawStats"
 		autogeneratedUrlDir  = "autogenerated-url"
@@ -430,7 +480,7 @@ func TestHandleRawStats(t *testing.T) {
 			inputTestExternalServerDir:          externalServerInvalidJsonDir,
 			inputTestExternalServerJsonFilename: externalServerInvalidJsonFile,
 			inputExternalServerReachable:        true,
-			expectedStatusCode:                  http.StatusInternalServerError,
+			expectedStatusCode:                  http.StatusBadGateway,
 			expectedResponse:                    "",
 		},
 		{
@@ -439,7 +489,7 @@ func TestHandleRawStats(t *testing.T) {
 			inputTestExternalServerDir:          unsupportedDir,
 			inputTestExternalServerJsonFilename: externalServerValidJsonFile,
 			inputExternalServerReachable:        true,
-			expectedStatusCode:                  http.StatusBadRequest,
+			expectedStatusCode:                  http.StatusNotFound,
 			expectedResponse:                    "",
 		},
 	}
@@ -516,14 +566,15 @@ func TestHandleRawStats(t *testing.T) {
 			}
 			apiServer := NewApiServer(svc)
 
-			handlerResp := apiServer.handleRawStats(rec, req)
-
 			if tc.inputTestExternalServerDir == unsupportedDir {
-				if handlerResp != nil {
+				apiServer.ServeHTTP(rec, req)
+				if rec.Code != tc.expectedStatusCode {
 					t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
-						tc.name, "nil", handlerResp.resp)
+						tc.name, tc.expectedStatusCode, rec.Code)
 				}
 			} else {
+				handlerResp := apiServer.handleRawStats(rec, req)
+
 				if handlerResp.StatusCode != tc.expectedStatusCode {
 					t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
 						tc.name, tc.expectedStatusCode, handlerResp.StatusCode)
@@ -542,6 +593,13 @@ func TestHandleRawStats(t *testing.T) {
 							tc.name, err.Error())
 					}
 
+					// Source durations are not deterministic. Only the outcome is compared
+					sources := handlerResp.resp.Sources
+					if len(sources) != 1 || !sources[0].Success || sources[0].Records != expectedResponseUrlStats.Count {
+						t.Fatalf("Test Failed: %v Unexpected Sources: %+v", tc.name, sources)
+					}
+					expectedResponseUrlStats.Sources = sources
+
 					assert := reflect.DeepEqual(expectedResponseUrlStats, handlerResp.resp)
 					if !assert {
 						t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
@@ -552,3 +610,130 @@ func TestHandleRawStats(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleSortKey_strictSources(t *testing.T) {
+	testInputUrlStatData := &types.UrlStatData{
+		Data: types.UrlStatSlice{
+			{Url: "www.example.com/abc1", Views: 1000, RelevanceScore: 0.5},
+		},
+		Sources: []*types.SourceStatus{
+			{Source: "http://localhost/success.json", Success: true, Records: 1},
+			{Source: "http://localhost/fail.json", Success: false, Error: "HTTP Get Failed"},
+		},
+	}
+
+	testCases := []struct {
+		name               string
+		inputQuery         string
+		expectedStatusCode int
+	}{
+		{
+			name:               "strictSources: not set - serve partial data",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "strictSources: false - serve partial data",
+			inputQuery:         "?" + strictSourcesOption + "=false",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "strictSources: true - fail on partial data",
+			inputQuery:         "?" + strictSourcesOption + "=true",
+			expectedStatusCode: http.StatusBadGateway,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			req := newSortKeyRequest(fmt.Sprintf("/%s/%s%s", sortkeyPath, viewsOption, tc.inputQuery))
+			rec := httptest.NewRecorder()
+
+			apiServer := NewApiServer(&stubService{data: testInputUrlStatData})
+			handlerResp := apiServer.handleSortKey(rec, req)
+
+			if handlerResp.StatusCode != tc.expectedStatusCode {
+				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
+					tc.name, tc.expectedStatusCode, handlerResp.StatusCode)
+			}
+			if handlerResp.Err != nil {
+				if handlerResp.Code != errCodeUpstreamPartialFailure {
+					t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
+						tc.name, errCodeUpstreamPartialFailure, handlerResp.Code)
+				}
+				return
+			}
+			if !reflect.DeepEqual(handlerResp.resp.Sources, testInputUrlStatData.Sources) {
+				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
+					tc.name, testInputUrlStatData.Sources, handlerResp.resp.Sources)
+			}
+		})
+	}
+}
+
+func TestHandleSortKey_filter(t *testing.T) {
+	testInputUrlStatData := &types.UrlStatData{
+		Data: types.UrlStatSlice{
+			{Url: "www.example.com/abc1", Views: 1000, RelevanceScore: 0.5},
+			{Url: "www.example.com/abc2", Views: 2000, RelevanceScore: 0.4},
+			{Url: "www.example.com/abc3", Views: 3000, RelevanceScore: 0.6},
+		},
+	}
+
+	testCases := []struct {
+		name               string
+		inputQuery         url.Values
+		expectedStatusCode int
+		expectedUrls       []string
+	}{
+		{
+			name:               "filter applied before sorting and limit",
+			inputQuery:         url.Values{minViewsFilterOption: {"2000"}, limitFilterOption: {"1"}},
+			expectedStatusCode: http.StatusOK,
+			expectedUrls:       []string{"www.example.com/abc3"},
+		},
+		{
+			name:               "filter expression",
+			inputQuery:         url.Values{expressionFilterOption: {"relevanceScore>=0.5"}},
+			expectedStatusCode: http.StatusOK,
+			expectedUrls:       []string{"www.example.com/abc3", "www.example.com/abc1"},
+		},
+		{
+			name:               "invalid filter expression",
+			inputQuery:         url.Values{expressionFilterOption: {"views >> 1"}},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			req := newSortKeyRequest(fmt.Sprintf("/%s/%s?%s", sortkeyPath, viewsOption+":desc", tc.inputQuery.Encode()))
+			rec := httptest.NewRecorder()
+
+			apiServer := NewApiServer(&stubService{data: testInputUrlStatData})
+			handlerResp := apiServer.handleSortKey(rec, req)
+
+			if handlerResp.StatusCode != tc.expectedStatusCode {
+				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
+					tc.name, tc.expectedStatusCode, handlerResp.StatusCode)
+			}
+			if handlerResp.Err != nil {
+				if handlerResp.Code != errCodeInvalidFilter {
+					t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
+						tc.name, errCodeInvalidFilter, handlerResp.Code)
+				}
+				return
+			}
+			if result := filteredUrls(*handlerResp.resp.SortedUrlStats); !reflect.DeepEqual(result, tc.expectedUrls) {
+				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
+					tc.name, tc.expectedUrls, result)
+			}
+			if len(testInputUrlStatData.Data) != 3 {
+				t.Fatalf("Test Failed: %v Input data must not be modified", tc.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+const (
+	defaultRefreshInterval = 30 * time.Second
+
+	// snapshotHistory is the number of snapshots kept for clients paging with a cursor
+	snapshotHistory = 5
+)
+
+// snapshotService is the cache the server depends on for readiness, pinned pages, metrics and shutdown
+type snapshotService interface {
+	service
+	getUrlStatsDataVersion(ctx context.Context, version uint64) (*types.UrlStatData, error)
+	getHealth() serviceHealth
+	getSnapshotStats() (snapshotStats, bool)
+	stop()
+}
+
+// wrappingService is implemented by decorators, so the server finds the cache behind them
+type wrappingService interface {
+	unwrapService() service
+}
+
+// findSnapshotService returns the cache behind svc and its decorators, or nil if there is none
+func findSnapshotService(svc service) snapshotService {
+	for svc != nil {
+		if snapshots, ok := svc.(snapshotService); ok {
+			return snapshots
+		}
+		wrapping, ok := svc.(wrappingService)
+		if !ok {
+			return nil
+		}
+		svc = wrapping.unwrapService()
+	}
+	return nil
+}
+
+type cachedSnapshot struct {
+	data        *types.UrlStatData
+	version     uint64
+	refreshedAt time.Time
+}
+
+type cachingService struct {
+	next            service
+	refreshInterval time.Duration
+
+	// refreshMu serializes calls to the next service, mu guards the cached snapshot
+	refreshMu   sync.Mutex
+	mu          sync.RWMutex
+	data        *types.UrlStatData
+	version     uint64
+	refreshedAt time.Time
+	lastErr     error
+
+	// lastSources is the outcome of each source in the last fetch cycle, successful or not
+	lastSources []*types.SourceStatus
+
+	// history holds the most recent snapshots, oldest first, including the current one
+	history []cachedSnapshot
+
+	// cancel stops the refresh loop, done is closed once it returned
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewCachingService(ctx context.Context, next service, refreshInterval time.Duration) service {
+	if refreshInterval <= 0 {
+		refreshInterval = defaultRefreshInterval
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c := &cachingService{
+		next:            next,
+		refreshInterval: refreshInterval,
+		cancel:          cancel,
+		done:            make(chan struct{}),
+	}
+	go func() {
+		defer close(c.done)
+		c.refreshLoop(ctx)
+	}()
+	return c
+}
+
+func (c *cachingService) getUrlStatsData(ctx context.Context) (*types.UrlStatData, error) {
+	data, snapshot := c.getSnapshot()
+	if data == nil {
+		if err := c.initialLoad(ctx); err != nil {
+			return nil, err
+		}
+		data, snapshot = c.getSnapshot()
+	}
+	return &types.UrlStatData{
+		Data:          data.Data,
+		Snapshot:      snapshot,
+		Sources:       data.Sources,
+		SortedIndexes: data.SortedIndexes,
+	}, nil
+}
+
+func (c *cachingService) getSnapshot() (*types.UrlStatData, *types.Snapshot) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.data == nil {
+		return nil, nil
+	}
+	return c.data, &types.Snapshot{
+		Version:     c.version,
+		RefreshedAt: c.refreshedAt,
+		AgeSeconds:  time.Since(c.refreshedAt).Seconds(),
+		Stale:       c.lastErr != nil,
+	}
+}
+
+func (c *cachingService) refreshLoop(ctx context.Context) {
+	ticker := time.NewTicker(c.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		// Every refresh gets its own request ID, so its upstream calls can be correlated
+		refreshCtx := contextWithRequestId(ctx, newRequestId())
+		if err := c.refresh(refreshCtx); err != nil && ctx.Err() == nil {
+			slog.WarnContext(refreshCtx, "Failed to refresh cached Url Stats Data. Serving stale data", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// stop cancels the refresh in progress, if any, and waits for the refresh loop to return
+func (c *cachingService) stop() {
+	c.cancel()
+	<-c.done
+}
+
+// initialLoad blocks callers until the first snapshot is available,
+// without triggering a second upstream fetch if the refresh loop got there first
+func (c *cachingService) initialLoad(ctx context.Context) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	if data, _ := c.getSnapshot(); data != nil {
+		return nil
+	}
+	return c.refreshLocked(ctx)
+}
+
+func (c *cachingService) refresh(ctx context.Context) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	return c.refreshLocked(ctx)
+}
+
+func (c *cachingService) refreshLocked(ctx context.Context) error {
+	data, err := c.next.getUrlStatsData(ctx)
+	if err == nil {
+		indexes := buildSortedIndexes(data.Data)
+		data = &types.UrlStatData{
+			Data:          data.Data,
+			Sources:       data.Sources,
+			SortedIndexes: indexes,
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		c.lastErr = err
+		var upstreamUnavailableErr *upstreamUnavailableError
+		if errors.As(err, &upstreamUnavailableErr) {
+			c.lastSources = upstreamUnavailableErr.sources
+		} else {
+			c.lastSources = nil
+		}
+		return err
+	}
+	c.data = data
+	c.lastSources = data.Sources
+	c.version++
+	c.refreshedAt = time.Now()
+	c.lastErr = nil
+
+	c.history = append(c.history, cachedSnapshot{data: data, version: c.version, refreshedAt: c.refreshedAt})
+	if len(c.history) > snapshotHistory {
+		c.history = c.history[len(c.history)-snapshotHistory:]
+	}
+	slog.InfoContext(ctx, "Cached snapshot",
+		"version", c.version, "records", len(data.Data),
+		"sortedIndexBytes", sortedIndexes(data.SortedIndexes).sizeBytes(),
+		"totalSortedIndexBytes", c.sortedIndexBytesLocked(), "snapshots", len(c.history))
+	return nil
+}
+
+// getHealth reports the current snapshot, if any, and the outcome of the last fetch cycle
+func (c *cachingService) getHealth() serviceHealth {
+	_, snapshot := c.getSnapshot()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return serviceHealth{
+		snapshot: snapshot,
+		lastErr:  c.lastErr,
+		sources:  c.lastSources,
+	}
+}
+
+func (c *cachingService) getSnapshotStats() (snapshotStats, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.data == nil {
+		return snapshotStats{}, false
+	}
+	return snapshotStats{
+		version:          c.version,
+		ageSeconds:       time.Since(c.refreshedAt).Seconds(),
+		snapshots:        len(c.history),
+		sortedIndexBytes: c.sortedIndexBytesLocked(),
+	}, true
+}
+
+// sortedIndexBytes is the memory held by the sorted indexes of every kept snapshot
+func (c *cachingService) sortedIndexBytes() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sortedIndexBytesLocked()
+}
+
+func (c *cachingService) sortedIndexBytesLocked() int {
+	var size int
+	for _, snapshot := range c.history {
+		size += sortedIndexes(snapshot.data.SortedIndexes).sizeBytes()
+	}
+	return size
+}
+
+// getUrlStatsDataVersion serves a kept snapshot. Snapshots older than the current one are stale,
+// as is the current one when the last refresh failed.
+func (c *cachingService) getUrlStatsDataVersion(ctx context.Context, version uint64) (*types.UrlStatData, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, snapshot := range c.history {
+		if snapshot.version != version {
+			continue
+		}
+		return &types.UrlStatData{
+			Data: snapshot.data.Data,
+			Snapshot: &types.Snapshot{
+				Version:     snapshot.version,
+				RefreshedAt: snapshot.refreshedAt,
+				AgeSeconds:  time.Since(snapshot.refreshedAt).Seconds(),
+				Stale:       snapshot.version != c.version || c.lastErr != nil,
+			},
+			Sources:       snapshot.data.Sources,
+			SortedIndexes: snapshot.data.SortedIndexes,
+		}, nil
+	}
+	return nil, &snapshotExpiredError{version: version}
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+type stubService struct {
+	mu    sync.Mutex
+	calls int
+	data  *types.UrlStatData
+	err   error
+}
+
+func (s *stubService) getUrlStatsData(ctx context.Context) (*types.UrlStatData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.data, nil
+}
+
+func (s *stubService) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *stubService) getCalls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func newStubUrlStatData() *types.UrlStatData {
+	return &types.UrlStatData{
+		Data: types.UrlStatSlice{
+			{Url: "www.example.com/abc1", Views: 1000, RelevanceScore: 0.5},
+		},
+	}
+}
+
+// newUrlStatTestData returns n records, www.example.com/abc1 to abcN, with 1000 views per index
+func newUrlStatTestData(n int) *types.UrlStatData {
+	data := &types.UrlStatData{}
+	for i := 1; i <= n; i++ {
+		data.Data = append(data.Data, &types.UrlStat{Url: fmt.Sprintf("www.example.com/abc%d", i), Views: i * 1000})
+	}
+	return data
+}
+
+func TestCachingService_ServesCachedData(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stub := &stubService{data: newStubUrlStatData()}
+	svc := NewCachingService(ctx, stub, time.Hour)
+
+	for i := 0; i < 5; i++ {
+		result, err := svc.getUrlStatsData(ctx)
+		if err != nil {
+			t.Fatalf("Test Failed. Unexpected Error: %v", err)
+		}
+		if len(result.Data) != len(stub.data.Data) {
+			t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v",
+				len(stub.data.Data), len(result.Data))
+		}
+		if result.Snapshot == nil || result.Snapshot.Stale {
+			t.Fatalf("Test Failed. Expected a fresh snapshot. Actual Result: %+v", result.Snapshot)
+		}
+	}
+
+	if calls := stub.getCalls(); calls != 1 {
+		t.Fatalf("Test Failed. Expected upstream calls: %v Actual upstream calls: %v", 1, calls)
+	}
+}
+
+func TestCachingService_ServesStaleDataOnRefreshFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stub := &stubService{data: newStubUrlStatData()}
+	c := NewCachingService(ctx, stub, time.Hour).(*cachingService)
+
+	if _, err := c.getUrlStatsData(ctx); err != nil {
+		t.Fatalf("Test Failed. Unexpected Error: %v", err)
+	}
+
+	stub.setErr(errors.New("upstream unavailable"))
+	if err := c.refresh(ctx); err == nil {
+		t.Fatalf("Test Failed. Expected refresh to fail")
+	}
+
+	result, err := c.getUrlStatsData(ctx)
+	if err != nil {
+		t.Fatalf("Test Failed. Expected stale data to be served. Error: %v", err)
+	}
+	if result.Snapshot == nil || !result.Snapshot.Stale {
+		t.Fatalf("Test Failed. Expected a stale snapshot. Actual Result: %+v", result.Snapshot)
+	}
+	if result.Snapshot.Version != 1 {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", 1, result.Snapshot.Version)
+	}
+}
+
+func TestCachingService_getUrlStatsDataVersion_Stale(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stub := &stubService{data: newStubUrlStatData()}
+	c := NewCachingService(ctx, stub, time.Hour).(*cachingService)
+	if _, err := c.getUrlStatsData(ctx); err != nil {
+		t.Fatalf("Internal Testing error: %v", err)
+	}
+	if err := c.refresh(ctx); err != nil {
+		t.Fatalf("Internal Testing error: %v", err)
+	}
+
+	testCases := []struct {
+		name          string
+		inputVersion  uint64
+		inputFailing  bool
+		expectedStale bool
+	}{
+		{
+			name:          "older snapshot",
+			inputVersion:  1,
+			expectedStale: true,
+		},
+		{
+			name:          "current snapshot",
+			inputVersion:  2,
+			expectedStale: false,
+		},
+		{
+			name:          "older snapshot, last refresh failed",
+			inputVersion:  1,
+			inputFailing:  true,
+			expectedStale: true,
+		},
+		{
+			name:          "current snapshot, last refresh failed",
+			inputVersion:  2,
+			inputFailing:  true,
+			expectedStale: true,
+		},
+	}
+
+	// The cases share the service and run in order, as the last ones make the refresh fail
+	for _, tc := range testCases {
+		if tc.inputFailing {
+			stub.setErr(errors.New("upstream unavailable"))
+			if err := c.refresh(ctx); err == nil {
+				t.Fatalf("Internal Testing error: expected refresh to fail")
+			}
+		}
+		result, err := c.getUrlStatsDataVersion(ctx, tc.inputVersion)
+		if err != nil {
+			t.Fatalf("Test Failed: %v. Unexpected Error: %v", tc.name, err)
+		}
+		if result.Snapshot.Version != tc.inputVersion || result.Snapshot.Stale != tc.expectedStale {
+			t.Fatalf("Test Failed: %v. Expected Result: version %v stale %v Actual Result: version %v stale %v",
+				tc.name, tc.inputVersion, tc.expectedStale, result.Snapshot.Version, result.Snapshot.Stale)
+		}
+	}
+}
+
+func TestCachingService_InitialLoadFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stub := &stubService{err: errors.New("upstream unavailable")}
+	svc := NewCachingService(ctx, stub, time.Hour)
+
+	result, err := svc.getUrlStatsData(ctx)
+	if err == nil {
+		t.Fatalf("Test Failed. Expected Error to occur. Actual Result: %+v", result)
+	}
+}
+
+func TestCachingService_BackgroundRefresh(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stub := &stubService{data: newStubUrlStatData()}
+	NewCachingService(ctx, stub, 10*time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for stub.getCalls() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Test Failed. Expected at least %v background refreshes. Actual: %v", 3, stub.getCalls())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,262 @@
+package api
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+const (
+	envVarCompressionMinSize = "COMPRESSION_MIN_SIZE"
+	envVarCompressionLevel   = "COMPRESSION_LEVEL"
+
+	defaultCompressionMinSize = 1024
+)
+
+// CompressionConfig controls the compression of responses. Responses smaller than MinSize
+// bytes are sent uncompressed. Level is the gzip compression level, from 1 (best speed)
+// to 9 (best compression), or -1 for the default level. Level 0 disables compression.
+type CompressionConfig struct {
+	MinSize int
+	Level   int
+}
+
+func DefaultCompressionConfig() CompressionConfig {
+	return CompressionConfig{
+		MinSize: defaultCompressionMinSize,
+		Level:   gzip.DefaultCompression,
+	}
+}
+
+// CompressionConfigFromEnv starts from the default config and applies the COMPRESSION_* environment variables
+func CompressionConfigFromEnv(getenv func(string) string) (CompressionConfig, error) {
+	config := DefaultCompressionConfig()
+	var err error
+	if value := getenv(envVarCompressionMinSize); value != "" {
+		if config.MinSize, err = strconv.Atoi(value); err != nil {
+			return config, fmt.Errorf("invalid %s: %w", envVarCompressionMinSize, err)
+		}
+	}
+	if value := getenv(envVarCompressionLevel); value != "" {
+		if config.Level, err = strconv.Atoi(value); err != nil {
+			return config, fmt.Errorf("invalid %s: %w", envVarCompressionLevel, err)
+		}
+	}
+	return config, config.validate()
+}
+
+func (c CompressionConfig) validate() error {
+	if c.MinSize < 0 {
+		return fmt.Errorf("compression: minimum size must not be negative. Got: %d", c.MinSize)
+	}
+	if c.Level < gzip.DefaultCompression || c.Level > gzip.BestCompression {
+		return fmt.Errorf("compression: level must be between %d and %d. Got: %d",
+			gzip.DefaultCompression, gzip.BestCompression, c.Level)
+	}
+	return nil
+}
+
+type encodingWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// contentEncoding compresses responses for one Accept-Encoding token. A new encoding only
+// needs an entry in contentEncodings.
+type contentEncoding struct {
+	name      string
+	newWriter func(w io.Writer, level int) encodingWriter
+	release   func(encodingWriter)
+}
+
+// contentEncodings is ordered by preference, for clients accepting several with the same quality
+var contentEncodings = []contentEncoding{
+	{name: "gzip", newWriter: newGzipWriter, release: releaseGzipWriter},
+}
+
+// gzipWriterPools holds a pool per compression level, from gzip.DefaultCompression to gzip.BestCompression
+var gzipWriterPools [gzip.BestCompression - gzip.DefaultCompression + 1]sync.Pool
+
+// pooledGzipWriter remembers its level, to be released into the right pool
+type pooledGzipWriter struct {
+	*gzip.Writer
+	level int
+}
+
+func newGzipWriter(w io.Writer, level int) encodingWriter {
+	if gz, ok := gzipWriterPools[level-gzip.DefaultCompression].Get().(*pooledGzipWriter); ok {
+		gz.Reset(w)
+		return gz
+	}
+	// The level is validated by CompressionConfig.validate
+	gz, _ := gzip.NewWriterLevel(w, level)
+	return &pooledGzipWriter{Writer: gz, level: level}
+}
+
+func releaseGzipWriter(w encodingWriter) {
+	gz := w.(*pooledGzipWriter)
+	// Resetting drops the reference to the response
+	gz.Reset(io.Discard)
+	gzipWriterPools[gz.level-gzip.DefaultCompression].Put(gz)
+}
+
+// negotiateContentEncoding returns nil when the client accepts none of the content encodings
+func negotiateContentEncoding(acceptEncoding string) *contentEncoding {
+	if acceptEncoding == "" {
+		return nil
+	}
+	ranges := parseAccept(acceptEncoding)
+	var best *contentEncoding
+	bestQuality := 0.0
+	for i, encoding := range contentEncodings {
+		quality := -1.0
+		for _, rng := range ranges {
+			if rng.mediaType == encoding.name {
+				quality = rng.quality
+				break
+			}
+			if rng.mediaType == "*" {
+				quality = rng.quality
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = &contentEncodings[i], quality
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the response until MinSize bytes were written, and only then commits
+// to compressing it. Smaller responses, and responses flushed before reaching MinSize, are sent
+// as they are.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding *contentEncoding
+	level    int
+	minSize  int
+
+	buf        []byte
+	statusCode int
+	committed  bool
+	ew         encodingWriter
+}
+
+func (cw *compressWriter) WriteHeader(statusCode int) {
+	if cw.committed {
+		cw.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	if cw.statusCode == 0 {
+		cw.statusCode = statusCode
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.committed {
+		if len(cw.buf)+len(b) < cw.minSize {
+			cw.buf = append(cw.buf, b...)
+			return len(b), nil
+		}
+		if err := cw.commit(true); err != nil {
+			return 0, err
+		}
+	}
+	if cw.ew != nil {
+		return cw.ew.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// commit sends the status line and the buffered bytes, compressed when requested and the
+// handler did not encode the response itself
+func (cw *compressWriter) commit(compress bool) error {
+	cw.committed = true
+	header := cw.ResponseWriter.Header()
+	if compress && header.Get("Content-Encoding") == "" {
+		header.Set("Content-Encoding", cw.encoding.name)
+		header.Del("Content-Length")
+		cw.ew = cw.encoding.newWriter(cw.ResponseWriter, cw.level)
+	}
+	if cw.statusCode != 0 {
+		cw.ResponseWriter.WriteHeader(cw.statusCode)
+	}
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.ew != nil {
+		_, err = cw.ew.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// FlushError is used by http.ResponseController. The compressed bytes written so far are
+// flushed along with the response.
+func (cw *compressWriter) FlushError() error {
+	if !cw.committed {
+		if err := cw.commit(false); err != nil {
+			return err
+		}
+	}
+	if cw.ew != nil {
+		if err := cw.ew.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the wrapped ResponseWriter for other features
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressWriter) close() error {
+	if !cw.committed {
+		if err := cw.commit(false); err != nil {
+			return err
+		}
+	}
+	if cw.ew == nil {
+		return nil
+	}
+	err := cw.ew.Close()
+	cw.encoding.release(cw.ew)
+	cw.ew = nil
+	return err
+}
+
+// compressHandler compresses the responses of next with the content encoding negotiated
+// through the Accept-Encoding header
+func (s *apiServer) compressHandler(next http.HandlerFunc) http.HandlerFunc {
+	if s.compression.Level == gzip.NoCompression {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateContentEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == nil {
+			next(w, r)
+			return
+		}
+		cw := &compressWriter{
+			ResponseWriter: w,
+			encoding:       encoding,
+			level:          s.compression.Level,
+			minSize:        s.compression.MinSize,
+		}
+		next(cw, r)
+		// Failing to write to a disconnected client is expected
+		if err := cw.close(); err != nil && r.Context().Err() == nil {
+			slog.WarnContext(r.Context(), "Failed to compress response", "path", r.URL.Path, "error", err)
+		}
+	}
+}
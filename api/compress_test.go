@@ -0,0 +1,333 @@
+package api
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateContentEncoding(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       string
+		expectedNil bool
+	}{
+		{name: "no Accept-Encoding", input: "", expectedNil: true},
+		{name: "gzip", input: "gzip"},
+		{name: "gzip among others", input: "br, gzip;q=0.8, deflate"},
+		{name: "upper case", input: "GZIP"},
+		{name: "any encoding", input: "*"},
+		{name: "identity only", input: "identity", expectedNil: true},
+		{name: "gzip excluded", input: "gzip;q=0", expectedNil: true},
+		{name: "gzip excluded, despite any encoding", input: "*, gzip;q=0", expectedNil: true},
+		{name: "unsupported encodings", input: "br, zstd", expectedNil: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result := negotiateContentEncoding(tc.input)
+			if (result == nil) != tc.expectedNil {
+				t.Fatalf("Test Failed: %v. Expected no encoding: %v Actual Result: %+v", tc.name, tc.expectedNil, result)
+			}
+			if result != nil && result.name != "gzip" {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v", tc.name, "gzip", result.name)
+			}
+		})
+	}
+}
+
+func TestCompressHandler(t *testing.T) {
+	testCases := []struct {
+		name                    string
+		inputMethod             string
+		inputPath               string
+		inputAcceptEncoding     string
+		inputRecords            int
+		inputConfig             CompressionConfig
+		expectedStatusCode      int
+		expectedContentEncoding string
+	}{
+		{
+			name:                    "gzip: large response compressed",
+			inputPath:               "/v1/sortkey/views",
+			inputAcceptEncoding:     "gzip",
+			inputRecords:            100,
+			inputConfig:             DefaultCompressionConfig(),
+			expectedStatusCode:      http.StatusOK,
+			expectedContentEncoding: "gzip",
+		},
+		{
+			name:                    "gzip: large csv response compressed",
+			inputPath:               "/v1/sortkey/views?format=csv",
+			inputAcceptEncoding:     "gzip",
+			inputRecords:            100,
+			inputConfig:             DefaultCompressionConfig(),
+			expectedStatusCode:      http.StatusOK,
+			expectedContentEncoding: "gzip",
+		},
+		{
+			name:                    "gzip: streamed response compressed",
+			inputPath:               "/v1/sortkey/views?format=ndjson",
+			inputAcceptEncoding:     "gzip",
+			inputRecords:            3 * streamFlushRecords,
+			inputConfig:             DefaultCompressionConfig(),
+			expectedStatusCode:      http.StatusOK,
+			expectedContentEncoding: "gzip",
+		},
+		{
+			name:                "gzip: small response below the minimum size",
+			inputPath:           "/v1/sortkey/views",
+			inputAcceptEncoding: "gzip",
+			inputRecords:        1,
+			inputConfig:         DefaultCompressionConfig(),
+			expectedStatusCode:  http.StatusOK,
+		},
+		{
+			name:                    "gzip: minimum size lowered",
+			inputPath:               "/v1/sortkey/views",
+			inputAcceptEncoding:     "gzip",
+			inputRecords:            1,
+			inputConfig:             CompressionConfig{MinSize: 10, Level: gzip.BestSpeed},
+			expectedStatusCode:      http.StatusOK,
+			expectedContentEncoding: "gzip",
+		},
+		{
+			name:                    "gzip: error response",
+			inputPath:               "/v1/sortkey/unsupported",
+			inputAcceptEncoding:     "gzip",
+			inputRecords:            1,
+			inputConfig:             CompressionConfig{MinSize: 10, Level: gzip.DefaultCompression},
+			expectedStatusCode:      http.StatusBadRequest,
+			expectedContentEncoding: "gzip",
+		},
+		{
+			name:                    "gzip: unmatched path",
+			inputPath:               "/v1/unknown",
+			inputAcceptEncoding:     "gzip",
+			inputConfig:             CompressionConfig{MinSize: 10, Level: gzip.DefaultCompression},
+			expectedStatusCode:      http.StatusNotFound,
+			expectedContentEncoding: "gzip",
+		},
+		{
+			name:                    "gzip: method not allowed",
+			inputMethod:             http.MethodPost,
+			inputPath:               "/v1/sortkey/views",
+			inputAcceptEncoding:     "gzip",
+			inputConfig:             CompressionConfig{MinSize: 10, Level: gzip.DefaultCompression},
+			expectedStatusCode:      http.StatusMethodNotAllowed,
+			expectedContentEncoding: "gzip",
+		},
+		{
+			name:               "identity: unmatched path not accepting gzip",
+			inputPath:          "/v1/unknown",
+			inputConfig:        DefaultCompressionConfig(),
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:                    "gzip: metrics",
+			inputPath:               metricsPath,
+			inputAcceptEncoding:     "gzip",
+			inputConfig:             DefaultCompressionConfig(),
+			expectedStatusCode:      http.StatusOK,
+			expectedContentEncoding: "gzip",
+		},
+		{
+			name:               "identity: not accepted",
+			inputPath:          "/v1/sortkey/views",
+			inputRecords:       100,
+			inputConfig:        DefaultCompressionConfig(),
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:                "identity: unsupported encoding",
+			inputPath:           "/v1/sortkey/views",
+			inputAcceptEncoding: "br, zstd",
+			inputRecords:        100,
+			inputConfig:         DefaultCompressionConfig(),
+			expectedStatusCode:  http.StatusOK,
+		},
+		{
+			name:                "identity: compression disabled",
+			inputPath:           "/v1/sortkey/views",
+			inputAcceptEncoding: "gzip",
+			inputRecords:        100,
+			inputConfig:         CompressionConfig{MinSize: 10, Level: gzip.NoCompression},
+			expectedStatusCode:  http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			data := newUrlStatTestData(tc.inputRecords)
+			method := tc.inputMethod
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			// The uncompressed response, served without compression
+			expectedRec := httptest.NewRecorder()
+			NewApiServer(&stubService{data: data}, WithCompression(CompressionConfig{Level: gzip.NoCompression})).
+				ServeHTTP(expectedRec, httptest.NewRequest(method, tc.inputPath, nil))
+
+			apiServer := NewApiServer(&stubService{data: data}, WithCompression(tc.inputConfig))
+			apiServer.metrics = newServerMetrics()
+			req := httptest.NewRequest(method, tc.inputPath, nil)
+			if tc.inputAcceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.inputAcceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			apiServer.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v", tc.name, tc.expectedStatusCode, rec.Code)
+			}
+			if contentEncoding := rec.Header().Get("Content-Encoding"); contentEncoding != tc.expectedContentEncoding {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v", tc.name, tc.expectedContentEncoding, contentEncoding)
+			}
+			if rec.Header().Get("Content-Type") != expectedRec.Header().Get("Content-Type") {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, expectedRec.Header().Get("Content-Type"), rec.Header().Get("Content-Type"))
+			}
+			vary := strings.Join(rec.Header().Values("Vary"), ", ")
+			if tc.inputConfig.Level != gzip.NoCompression && !strings.Contains(vary, "Accept-Encoding") {
+				t.Fatalf("Test Failed: %v. Expected Vary to contain: %v Actual Result: %v", tc.name, "Accept-Encoding", vary)
+			}
+
+			body := rec.Body.String()
+			if tc.expectedContentEncoding == "gzip" {
+				gz, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("Test Failed: %v. Invalid gzip body: %v", tc.name, err)
+				}
+				decompressed, err := io.ReadAll(gz)
+				if err != nil {
+					t.Fatalf("Test Failed: %v. Invalid gzip body: %v", tc.name, err)
+				}
+				// Tiny bodies grow when compressed, which is what the minimum size prevents
+				if tc.inputRecords >= 100 && len(decompressed) <= len(body) {
+					t.Fatalf("Test Failed: %v. Expected the body to be compressed. Compressed: %v Decompressed: %v",
+						tc.name, len(body), len(decompressed))
+				}
+				body = string(decompressed)
+			}
+			// Metrics and error bodies, carrying request IDs, differ between both responses
+			if tc.inputPath != metricsPath && tc.expectedStatusCode == http.StatusOK && body != expectedRec.Body.String() {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v", tc.name, expectedRec.Body.String(), body)
+			}
+		})
+	}
+}
+
+func TestCompressHandler_streamFlush(t *testing.T) {
+	apiServer := NewApiServer(&stubService{data: newUrlStatTestData(2*streamFlushRecords + 500)})
+	req := httptest.NewRequest(http.MethodGet, "/v1/sortkey/views?format=ndjson", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	apiServer.ServeHTTP(rec, req)
+
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", "gzip", rec.Header().Get("Content-Encoding"))
+	}
+	if rec.flushes != 3 {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", 3, rec.flushes)
+	}
+}
+
+func TestCompressHandler_httpClient(t *testing.T) {
+	s := httptest.NewServer(NewApiServer(&stubService{data: newUrlStatTestData(100)}))
+	defer s.Close()
+
+	// The default transport requests gzip and transparently decompresses the body
+	resp, err := http.Get(s.URL + "/v1/sortkey/views?format=csv")
+	if err != nil {
+		t.Fatalf("Internal Testing error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Internal Testing error: %v", err)
+	}
+	if !resp.Uncompressed {
+		t.Fatalf("Test Failed. Expected the response to be compressed")
+	}
+	if lines := strings.Count(string(body), "\n"); lines != 101 {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", 101, lines)
+	}
+}
+
+func TestCompressionConfigFromEnv(t *testing.T) {
+	testCases := []struct {
+		name        string
+		inputEnv    map[string]string
+		expected    CompressionConfig
+		expectedErr bool
+	}{
+		{
+			name:     "no env: default config",
+			expected: DefaultCompressionConfig(),
+		},
+		{
+			name: "all settings",
+			inputEnv: map[string]string{
+				envVarCompressionMinSize: "512",
+				envVarCompressionLevel:   "9",
+			},
+			expected: CompressionConfig{MinSize: 512, Level: gzip.BestCompression},
+		},
+		{
+			name: "compression disabled",
+			inputEnv: map[string]string{
+				envVarCompressionLevel: "0",
+			},
+			expected: CompressionConfig{MinSize: defaultCompressionMinSize, Level: gzip.NoCompression},
+		},
+		{
+			name: "invalid level",
+			inputEnv: map[string]string{
+				envVarCompressionLevel: "10",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "negative minimum size",
+			inputEnv: map[string]string{
+				envVarCompressionMinSize: "-1",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid minimum size",
+			inputEnv: map[string]string{
+				envVarCompressionMinSize: "1kb",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result, resultErr := CompressionConfigFromEnv(func(key string) string {
+				return tc.inputEnv[key]
+			})
+			assertErr := resultErr != nil
+			if assertErr != tc.expectedErr {
+				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
+					tc.name, tc.expectedErr, resultErr)
+			}
+			if !tc.expectedErr && !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %+v Actual Result: %+v",
+					tc.name, tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+const (
+	formatOption = "format"
+
+	contentTypeCsv    = "text/csv"
+	contentTypeNdjson = "application/x-ndjson"
+)
+
+var csvHeader = []string{urlOption, viewsOption, relevancescoreOption}
+
+// urlStatsEncoder writes successful responses in one output format. A new format only
+// needs an entry in urlStatsEncoders.
+type urlStatsEncoder struct {
+	// format is the value selecting the encoder in the format query parameter
+	format      string
+	contentType string
+	encode      func(sw *streamWriter, resp *types.ResponseUrlStats) error
+}
+
+// urlStatsEncoders is ordered by preference. The first one is the default.
+var urlStatsEncoders = []urlStatsEncoder{
+	{format: "json", contentType: contentTypeJson, encode: encodeJson},
+	{format: "csv", contentType: contentTypeCsv, encode: encodeCsv},
+	{format: "ndjson", contentType: contentTypeNdjson, encode: encodeNdjson},
+}
+
+// notAcceptableError is returned when none of the media types accepted by the client can be produced
+type notAcceptableError struct {
+	accept string
+}
+
+func (e *notAcceptableError) Error() string {
+	return fmt.Sprintf("none of the accepted media types %q can be produced. Supported media types: %s",
+		e.accept, strings.Join(getSupportedContentTypes(), ", "))
+}
+
+func getSupportedContentTypes() []string {
+	var contentTypes []string
+	for _, encoder := range urlStatsEncoders {
+		contentTypes = append(contentTypes, encoder.contentType)
+	}
+	return contentTypes
+}
+
+// negotiateEncoder honours the format query parameter and falls back to the Accept header
+func negotiateEncoder(r *http.Request) (urlStatsEncoder, error) {
+	if format := r.URL.Query().Get(formatOption); format != "" {
+		for _, encoder := range urlStatsEncoders {
+			if format == encoder.format {
+				return encoder, nil
+			}
+		}
+		var formats []string
+		for _, encoder := range urlStatsEncoders {
+			formats = append(formats, encoder.format)
+		}
+		return urlStatsEncoder{}, &invalidRequestError{
+			code: errCodeInvalidFormat,
+			err:  fmt.Errorf("invalid %s %q. Valid formats: %s", formatOption, format, strings.Join(formats, ", ")),
+		}
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return urlStatsEncoders[0], nil
+	}
+	ranges := parseAccept(accept)
+	best, bestQuality, bestSpecificity := -1, 0.0, -1
+	for i, encoder := range urlStatsEncoders {
+		quality, specificity := ranges.match(encoder.contentType)
+		if quality > bestQuality || (quality == bestQuality && quality > 0 && specificity > bestSpecificity) {
+			best, bestQuality, bestSpecificity = i, quality, specificity
+		}
+	}
+	if best < 0 {
+		return urlStatsEncoder{}, &notAcceptableError{accept: accept}
+	}
+	return urlStatsEncoders[best], nil
+}
+
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+type mediaRanges []mediaRange
+
+// parseAccept parses Accept and Accept-Encoding headers. Parameters other than the quality
+// value "q" are ignored.
+func parseAccept(accept string) mediaRanges {
+	var ranges mediaRanges
+	for _, segment := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(segment, ";")
+		rng := mediaRange{mediaType: strings.ToLower(strings.TrimSpace(mediaType)), quality: 1}
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(key) != "q" {
+				continue
+			}
+			quality, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || quality < 0 || quality > 1 {
+				quality = 0
+			}
+			rng.quality = quality
+		}
+		if rng.mediaType != "" {
+			ranges = append(ranges, rng)
+		}
+	}
+	return ranges
+}
+
+// match returns the quality of the most specific range matching contentType. Specificity is
+// 2 for an exact match, 1 for "type/*" and 0 for "*/*".
+func (ranges mediaRanges) match(contentType string) (quality float64, specificity int) {
+	specificity = -1
+	mainType, _, _ := strings.Cut(contentType, "/")
+	for _, rng := range ranges {
+		var s int
+		switch rng.mediaType {
+		case contentType:
+			s = 2
+		case mainType + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			quality, specificity = rng.quality, s
+		}
+	}
+	return quality, specificity
+}
+
+// responseDataPrefix is how every JSON encoded types.ResponseUrlStats without records starts,
+// as data is its first member and is never omitted
+var responseDataPrefix = []byte(`{"data":null`)
+
+// encodeJson streams the records of the data member one by one, followed by the other members
+func encodeJson(sw *streamWriter, resp *types.ResponseUrlStats) error {
+	if resp == nil || resp.SortedUrlStats == nil {
+		if err := json.NewEncoder(sw).Encode(resp); err != nil {
+			return err
+		}
+		return sw.flush()
+	}
+	meta := *resp
+	meta.SortedUrlStats = nil
+	metaJson, err := json.Marshal(&meta)
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(metaJson, responseDataPrefix) {
+		return fmt.Errorf("unexpected response encoding: %s", metaJson)
+	}
+
+	if _, err := io.WriteString(sw, `{"data":[`); err != nil {
+		return err
+	}
+	// The record buffer is reused, dropping the newline the encoder appends
+	var urlStatJson bytes.Buffer
+	encoder := json.NewEncoder(&urlStatJson)
+	for i, urlStat := range *resp.SortedUrlStats {
+		if i > 0 {
+			if _, err := io.WriteString(sw, ","); err != nil {
+				return err
+			}
+		}
+		urlStatJson.Reset()
+		if err := encoder.Encode(urlStat); err != nil {
+			return err
+		}
+		if _, err := sw.Write(bytes.TrimSuffix(urlStatJson.Bytes(), []byte("\n"))); err != nil {
+			return err
+		}
+		if err := sw.recordWritten(); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(sw, "]"); err != nil {
+		return err
+	}
+	if _, err := sw.Write(metaJson[len(responseDataPrefix):]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(sw, "\n"); err != nil {
+		return err
+	}
+	return sw.flush()
+}
+
+// encodeCsv only writes the records. The paging metadata is only part of the JSON format.
+func encodeCsv(sw *streamWriter, resp *types.ResponseUrlStats) error {
+	csvWriter := csv.NewWriter(sw)
+	if err := csvWriter.Write(csvHeader); err != nil {
+		return err
+	}
+	row := make([]string, len(csvHeader))
+	for _, urlStat := range getResponseUrlStats(resp) {
+		if urlStat == nil {
+			continue
+		}
+		row[0] = urlStat.Url
+		row[1] = strconv.Itoa(urlStat.Views)
+		row[2] = strconv.FormatFloat(float64(urlStat.RelevanceScore), 'f', -1, 32)
+		if err := csvWriter.Write(row); err != nil {
+			return err
+		}
+		// Hand each row over to the stream writer, which decides when to flush
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return err
+		}
+		if err := sw.recordWritten(); err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return err
+	}
+	return sw.flush()
+}
+
+// encodeNdjson writes one JSON record per line. The paging metadata is only part of the JSON format.
+func encodeNdjson(sw *streamWriter, resp *types.ResponseUrlStats) error {
+	encoder := json.NewEncoder(sw)
+	for _, urlStat := range getResponseUrlStats(resp) {
+		if urlStat == nil {
+			continue
+		}
+		if err := encoder.Encode(urlStat); err != nil {
+			return err
+		}
+		if err := sw.recordWritten(); err != nil {
+			return err
+		}
+	}
+	return sw.flush()
+}
+
+func getResponseUrlStats(resp *types.ResponseUrlStats) types.UrlStatSlice {
+	if resp == nil || resp.SortedUrlStats == nil {
+		return nil
+	}
+	return *resp.SortedUrlStats
+}
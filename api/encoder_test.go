@@ -0,0 +1,286 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+func TestNegotiateEncoder(t *testing.T) {
+	testCases := []struct {
+		name           string
+		inputAccept    string
+		inputQuery     string
+		expectedFormat string
+		expectedCode   string
+	}{
+		{
+			name:           "no Accept header: json",
+			expectedFormat: "json",
+		},
+		{
+			name:           "any media type: json",
+			inputAccept:    "*/*",
+			expectedFormat: "json",
+		},
+		{
+			name:           "csv",
+			inputAccept:    contentTypeCsv,
+			expectedFormat: "csv",
+		},
+		{
+			name:           "ndjson with parameters",
+			inputAccept:    contentTypeNdjson + "; charset=utf-8",
+			expectedFormat: "ndjson",
+		},
+		{
+			name:           "specific media type preferred over wildcard",
+			inputAccept:    "*/*, text/csv",
+			expectedFormat: "csv",
+		},
+		{
+			name:           "highest quality preferred",
+			inputAccept:    "text/csv;q=0.5, application/x-ndjson;q=0.9",
+			expectedFormat: "ndjson",
+		},
+		{
+			name:           "type wildcard",
+			inputAccept:    "text/*",
+			expectedFormat: "csv",
+		},
+		{
+			name:           "browser: json",
+			inputAccept:    "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			expectedFormat: "json",
+		},
+		{
+			name:           "format takes precedence over Accept",
+			inputAccept:    contentTypeJson,
+			inputQuery:     "?format=csv",
+			expectedFormat: "csv",
+		},
+		{
+			name:         "unsupported media type",
+			inputAccept:  "application/xml",
+			expectedCode: errCodeNotAcceptable,
+		},
+		{
+			name:         "excluded media type",
+			inputAccept:  "text/csv;q=0",
+			expectedCode: errCodeNotAcceptable,
+		},
+		{
+			name:         "unsupported format",
+			inputQuery:   "?format=xml",
+			expectedCode: errCodeInvalidFormat,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodGet, "/"+tc.inputQuery, nil)
+			if tc.inputAccept != "" {
+				req.Header.Set("Accept", tc.inputAccept)
+			}
+			result, resultErr := negotiateEncoder(req)
+
+			if tc.expectedCode != "" {
+				if resultErr == nil {
+					t.Fatalf("Test Failed: %v. Expected Error to occur. Actual Result: %v", tc.name, result.format)
+				}
+				if code := newErrorHandlerResponse(resultErr).Code; code != tc.expectedCode {
+					t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v", tc.name, tc.expectedCode, code)
+				}
+				return
+			}
+			if resultErr != nil {
+				t.Fatalf("Test Failed: %v. Returned Error: %v", tc.name, resultErr)
+			}
+			if result.format != tc.expectedFormat {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v", tc.name, tc.expectedFormat, result.format)
+			}
+		})
+	}
+}
+
+func TestEncoders(t *testing.T) {
+	testInputResp := &types.ResponseUrlStats{
+		SortedUrlStats: &types.UrlStatSlice{
+			{Url: "www.example.com/abc1", Views: 1000, RelevanceScore: 0.5},
+			nil,
+			{Url: "www.example.com/a,b", Views: 0, RelevanceScore: 0.12},
+		},
+		Count: 3,
+		Total: 3,
+	}
+
+	testCases := []struct {
+		name        string
+		inputEncode func(sw *streamWriter, resp *types.ResponseUrlStats) error
+		inputResp   *types.ResponseUrlStats
+		expected    string
+	}{
+		{
+			name:        "csv",
+			inputEncode: encodeCsv,
+			inputResp:   testInputResp,
+			expected:    "url,views,relevanceScore\nwww.example.com/abc1,1000,0.5\n\"www.example.com/a,b\",0,0.12\n",
+		},
+		{
+			name:        "csv: no records",
+			inputEncode: encodeCsv,
+			inputResp:   &types.ResponseUrlStats{},
+			expected:    "url,views,relevanceScore\n",
+		},
+		{
+			name:        "ndjson",
+			inputEncode: encodeNdjson,
+			inputResp:   testInputResp,
+			expected:    "{\"url\":\"www.example.com/abc1\",\"views\":1000,\"relevanceScore\":0.5}\n{\"url\":\"www.example.com/a,b\",\"relevanceScore\":0.12}\n",
+		},
+		{
+			name:        "ndjson: no records",
+			inputEncode: encodeNdjson,
+			inputResp:   &types.ResponseUrlStats{},
+			expected:    "",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			if err := tc.inputEncode(newStreamWriter(context.Background(), &buf), tc.inputResp); err != nil {
+				t.Fatalf("Test Failed: %v. Returned Error: %v", tc.name, err)
+			}
+			if buf.String() != tc.expected {
+				t.Fatalf("Test Failed: %v. Expected Result: %q Actual Result: %q", tc.name, tc.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestEncodeJson(t *testing.T) {
+	testCases := []struct {
+		name      string
+		inputResp *types.ResponseUrlStats
+	}{
+		{
+			name: "records and metadata",
+			inputResp: &types.ResponseUrlStats{
+				SortedUrlStats: &types.UrlStatSlice{
+					{Url: "www.example.com/abc1", Views: 1000, RelevanceScore: 0.5},
+					nil,
+					{Url: "www.example.com/<abc2>", NormalizedUrl: "https://www.example.com/<abc2>", Sources: []string{"a.json", "b.json"}},
+				},
+				Count:      3,
+				Total:      10,
+				HasMore:    true,
+				NextCursor: "next",
+				Snapshot:   &types.Snapshot{Version: 2},
+				Sources:    []*types.SourceStatus{{Source: "a.json", Success: true, Records: 3}},
+			},
+		},
+		{
+			name:      "no records",
+			inputResp: &types.ResponseUrlStats{SortedUrlStats: &types.UrlStatSlice{}},
+		},
+		{
+			name:      "nil records",
+			inputResp: &types.ResponseUrlStats{},
+		},
+		{
+			name: "nil response",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var expected bytes.Buffer
+			if err := json.NewEncoder(&expected).Encode(tc.inputResp); err != nil {
+				t.Fatalf("Internal Testing error: %v", err)
+			}
+			var result bytes.Buffer
+			if err := encodeJson(newStreamWriter(context.Background(), &result), tc.inputResp); err != nil {
+				t.Fatalf("Test Failed: %v. Returned Error: %v", tc.name, err)
+			}
+			if result.String() != expected.String() {
+				t.Fatalf("Test Failed: %v. Expected Result: %q Actual Result: %q", tc.name, expected.String(), result.String())
+			}
+		})
+	}
+}
+
+func TestHandleSortKey_format(t *testing.T) {
+	testCases := []struct {
+		name                string
+		inputPath           string
+		inputAccept         string
+		expectedStatusCode  int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "csv via Accept",
+			inputPath:           "/v1/sortkey/views",
+			inputAccept:         contentTypeCsv,
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: contentTypeCsv,
+			expectedBody:        "url,views,relevanceScore\nwww.example.com/abc1,1000,0.5\n",
+		},
+		{
+			name:                "ndjson via format",
+			inputPath:           "/v1/?format=ndjson",
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: contentTypeNdjson,
+			expectedBody:        "{\"url\":\"www.example.com/abc1\",\"views\":1000,\"relevanceScore\":0.5}\n",
+		},
+		{
+			name:                "not acceptable",
+			inputPath:           "/v1/sortkey/views",
+			inputAccept:         "application/xml",
+			expectedStatusCode:  http.StatusNotAcceptable,
+			expectedContentType: contentTypeProblemJson,
+		},
+		{
+			name:                "errors are served as problem+json",
+			inputPath:           "/v1/sortkey/unsupported?format=csv",
+			expectedStatusCode:  http.StatusBadRequest,
+			expectedContentType: contentTypeProblemJson,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			apiServer := NewApiServer(&stubService{data: newStubUrlStatData()})
+			req := httptest.NewRequest(http.MethodGet, tc.inputPath, nil)
+			if tc.inputAccept != "" {
+				req.Header.Set("Accept", tc.inputAccept)
+			}
+			rec := httptest.NewRecorder()
+			apiServer.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v", tc.name, tc.expectedStatusCode, rec.Code)
+			}
+			if contentType := rec.Header().Get("Content-Type"); contentType != tc.expectedContentType {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v", tc.name, tc.expectedContentType, contentType)
+			}
+			if tc.expectedBody != "" && rec.Body.String() != tc.expectedBody {
+				t.Fatalf("Test Failed: %v. Expected Result: %q Actual Result: %q", tc.name, tc.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
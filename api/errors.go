@@ -0,0 +1,194 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+// Machine-readable error codes returned in the "code" member of error responses
+const (
+	errCodeInvalidSortKey         = "invalid_sort_key"
+	errCodeInvalidFilter          = "invalid_filter"
+	errCodeInvalidCursor          = "invalid_cursor"
+	errCodeInvalidFormat          = "invalid_format"
+	errCodeCursorExpired          = "cursor_expired"
+	errCodeNotFound               = "not_found"
+	errCodeMethodNotAllowed       = "method_not_allowed"
+	errCodeNotAcceptable          = "not_acceptable"
+	errCodeUpstreamUnavailable    = "upstream_unavailable"
+	errCodeUpstreamPartialFailure = "upstream_partial_failure"
+	errCodeDataSourceConfig       = "data_source_config_error"
+	errCodeInternal               = "internal_error"
+)
+
+// invalidRequestError is returned when the request itself can not be served
+type invalidRequestError struct {
+	code string
+	err  error
+}
+
+func (e *invalidRequestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *invalidRequestError) Unwrap() error {
+	return e.err
+}
+
+// snapshotExpiredError is returned when a cursor refers to a snapshot that is no longer kept
+type snapshotExpiredError struct {
+	version uint64
+}
+
+func (e *snapshotExpiredError) Error() string {
+	return fmt.Sprintf("snapshot %d is no longer available. Restart paging without a cursor", e.version)
+}
+
+// dataSourceConfigError is returned when the configured Data Source can not be used,
+// e.g. missing folders, no config files or no valid urls within them
+type dataSourceConfigError struct {
+	err error
+}
+
+func (e *dataSourceConfigError) Error() string {
+	return fmt.Sprintf("invalid Data Source configuration: %v", e.err)
+}
+
+func (e *dataSourceConfigError) Unwrap() error {
+	return e.err
+}
+
+// upstreamError is returned when a single HTTP Data Source Endpoint fails.
+// StatusCode is 0 when no HTTP response was received.
+type upstreamError struct {
+	Url        string
+	StatusCode int
+	Err        error
+}
+
+func (e *upstreamError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("HTTP Get to %v Failed. HTTP Response: %d - %s", e.Url, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("HTTP Get to %v Failed. Error: %v", e.Url, e.Err)
+}
+
+func (e *upstreamError) Unwrap() error {
+	return e.Err
+}
+
+// invalidPayloadError is returned when a Data Source payload can not be decoded or fails validation
+type invalidPayloadError struct {
+	err error
+}
+
+func (e *invalidPayloadError) Error() string {
+	return fmt.Sprintf("invalid payload: %v", e.err)
+}
+
+func (e *invalidPayloadError) Unwrap() error {
+	return e.err
+}
+
+// upstreamUnavailableError is returned when no HTTP Data Source Endpoint returned data.
+// sources reports the outcome of each endpoint, as a successful load would.
+type upstreamUnavailableError struct {
+	errs    []error
+	sources []*types.SourceStatus
+}
+
+func (e *upstreamUnavailableError) Error() string {
+	return fmt.Sprintf("all %v http get attempts failed", len(e.errs))
+}
+
+func (e *upstreamUnavailableError) Unwrap() []error {
+	return e.errs
+}
+
+// statusCode is 503 when none of the upstreams could be reached and 502 when
+// at least one of them answered with an invalid response
+func (e *upstreamUnavailableError) statusCode() int {
+	if len(e.errs) == 0 {
+		return http.StatusBadGateway
+	}
+	for _, err := range e.errs {
+		var upstreamErr *upstreamError
+		var invalidPayloadErr *invalidPayloadError
+		if !errors.As(err, &upstreamErr) || upstreamErr.StatusCode != 0 || errors.As(err, &invalidPayloadErr) {
+			return http.StatusBadGateway
+		}
+	}
+	return http.StatusServiceUnavailable
+}
+
+// partialFailureError is returned when some, but not all, HTTP Data Source Endpoints failed
+type partialFailureError struct {
+	total int
+	errs  []error
+}
+
+func (e *partialFailureError) Error() string {
+	return fmt.Sprintf("%v out of %v http get attempts failed", len(e.errs), e.total)
+}
+
+func (e *partialFailureError) Unwrap() []error {
+	return e.errs
+}
+
+// newPartialFailureError returns nil when every source succeeded
+func newPartialFailureError(sources []*types.SourceStatus) error {
+	var errs []error
+	for _, source := range sources {
+		if !source.Success {
+			errs = append(errs, fmt.Errorf("%s: %s", source.Source, source.Error))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &partialFailureError{total: len(sources), errs: errs}
+}
+
+func newErrorHandlerResponse(err error) *handlerResponse {
+	var (
+		invalidRequestErr      *invalidRequestError
+		upstreamUnavailableErr *upstreamUnavailableError
+		partialFailureErr      *partialFailureError
+		dataSourceConfigErr    *dataSourceConfigError
+		snapshotExpiredErr     *snapshotExpiredError
+		notAcceptableErr       *notAcceptableError
+	)
+	switch {
+	case errors.As(err, &invalidRequestErr):
+		return &handlerResponse{Err: err, StatusCode: http.StatusBadRequest, Code: invalidRequestErr.code}
+	case errors.As(err, &notAcceptableErr):
+		return &handlerResponse{Err: err, StatusCode: http.StatusNotAcceptable, Code: errCodeNotAcceptable}
+	case errors.As(err, &snapshotExpiredErr):
+		return &handlerResponse{Err: err, StatusCode: http.StatusGone, Code: errCodeCursorExpired}
+	case errors.As(err, &upstreamUnavailableErr):
+		return &handlerResponse{Err: err, StatusCode: upstreamUnavailableErr.statusCode(), Code: errCodeUpstreamUnavailable}
+	case errors.As(err, &partialFailureErr):
+		return &handlerResponse{Err: err, StatusCode: http.StatusBadGateway, Code: errCodeUpstreamPartialFailure}
+	case errors.As(err, &dataSourceConfigErr):
+		return &handlerResponse{Err: err, StatusCode: http.StatusInternalServerError, Code: errCodeDataSourceConfig}
+	default:
+		return &handlerResponse{Err: err, StatusCode: http.StatusInternalServerError, Code: errCodeInternal}
+	}
+}
+
+// getErrorCode falls back to a code derived from the HTTP status
+// for handler responses that did not set one explicitly
+func getErrorCode(handlerResp *handlerResponse) string {
+	if handlerResp.Code != "" {
+		return handlerResp.Code
+	}
+	statusText := http.StatusText(handlerResp.StatusCode)
+	if statusText == "" {
+		return errCodeInternal
+	}
+	return strings.ReplaceAll(strings.ToLower(statusText), " ", "_")
+}
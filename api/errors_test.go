@@ -0,0 +1,93 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestNewErrorHandlerResponse(t *testing.T) {
+	const testUrl = "http://localhost/test.json"
+
+	var (
+		transportErr = &upstreamError{Url: testUrl, Err: errors.New("connection refused")}
+		statusErr    = &upstreamError{Url: testUrl, StatusCode: http.StatusNotFound}
+	)
+
+	testCases := []struct {
+		name               string
+		inputErr           error
+		expectedStatusCode int
+		expectedCode       string
+	}{
+		{
+			name:               "invalid request",
+			inputErr:           &invalidRequestError{code: errCodeInvalidFilter, err: errors.New("bad filter")},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       errCodeInvalidFilter,
+		},
+		{
+			name:               "invalid sort key",
+			inputErr:           &invalidRequestError{code: errCodeInvalidSortKey, err: &invalidSortKeyError{segment: "foo"}},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       errCodeInvalidSortKey,
+		},
+		{
+			name:               "all upstreams unreachable",
+			inputErr:           &upstreamUnavailableError{errs: []error{transportErr, transportErr}},
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedCode:       errCodeUpstreamUnavailable,
+		},
+		{
+			name:               "all upstreams failed, at least one with invalid response",
+			inputErr:           &upstreamUnavailableError{errs: []error{transportErr, statusErr}},
+			expectedStatusCode: http.StatusBadGateway,
+			expectedCode:       errCodeUpstreamUnavailable,
+		},
+		{
+			name:               "upstreams returned no data",
+			inputErr:           &upstreamUnavailableError{},
+			expectedStatusCode: http.StatusBadGateway,
+			expectedCode:       errCodeUpstreamUnavailable,
+		},
+		{
+			name:               "partial upstream failure",
+			inputErr:           &partialFailureError{total: 2, errs: []error{statusErr}},
+			expectedStatusCode: http.StatusBadGateway,
+			expectedCode:       errCodeUpstreamPartialFailure,
+		},
+		{
+			name:               "wrapped data source config error",
+			inputErr:           fmt.Errorf("wrapped: %w", &dataSourceConfigError{errors.New("empty folder")}),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedCode:       errCodeDataSourceConfig,
+		},
+		{
+			name:               "untyped error",
+			inputErr:           errors.New("untyped"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedCode:       errCodeInternal,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result := newErrorHandlerResponse(tc.inputErr)
+			if result.StatusCode != tc.expectedStatusCode {
+				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
+					tc.name, tc.expectedStatusCode, result.StatusCode)
+			}
+			if result.Code != tc.expectedCode {
+				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
+					tc.name, tc.expectedCode, result.Code)
+			}
+			if !errors.Is(result.Err, tc.inputErr) {
+				t.Fatalf("Test Failed: %v Expected Error %v to be carried. Actual Error: %v",
+					tc.name, tc.inputErr, result.Err)
+			}
+		})
+	}
+}
@@ -0,0 +1,399 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+const (
+	minViewsFilterOption     = "minViews"
+	maxViewsFilterOption     = "maxViews"
+	minRelevanceFilterOption = "minRelevance"
+	maxRelevanceFilterOption = "maxRelevance"
+	hostFilterOption         = "host"
+	urlPrefixFilterOption    = "urlPrefix"
+	urlMatchFilterOption     = "urlMatch"
+	expressionFilterOption   = "filter"
+)
+
+type urlStatPredicate func(*types.UrlStat) bool
+
+// urlStatFilter keeps the records matching every predicate
+type urlStatFilter []urlStatPredicate
+
+// apply returns a new slice, so the (possibly cached) input is left untouched
+func (f urlStatFilter) apply(items types.UrlStatSlice) types.UrlStatSlice {
+	if len(f) == 0 {
+		return items
+	}
+	filtered := make(types.UrlStatSlice, 0, len(items))
+	for _, urlStat := range items {
+		if urlStat != nil && f.matches(urlStat) {
+			filtered = append(filtered, urlStat)
+		}
+	}
+	return filtered
+}
+
+func (f urlStatFilter) matches(urlStat *types.UrlStat) bool {
+	for _, predicate := range f {
+		if !predicate(urlStat) {
+			return false
+		}
+	}
+	return true
+}
+
+type invalidFilterError struct {
+	option string
+	value  string
+	err    error
+}
+
+func (e *invalidFilterError) Error() string {
+	return fmt.Sprintf("invalid %s %q: %v", e.option, e.value, e.err)
+}
+
+func (e *invalidFilterError) Unwrap() error {
+	return e.err
+}
+
+// parseFilters builds the filter out of the minViews, maxViews, minRelevance, maxRelevance,
+// host, urlPrefix, urlMatch and filter query parameters
+func parseFilters(query url.Values) (urlStatFilter, error) {
+	var filter urlStatFilter
+
+	numericOptions := []struct {
+		option string
+		field  string
+		op     string
+	}{
+		{minViewsFilterOption, viewsOption, ">="},
+		{maxViewsFilterOption, viewsOption, "<="},
+		{minRelevanceFilterOption, relevancescoreOption, ">="},
+		{maxRelevanceFilterOption, relevancescoreOption, "<="},
+	}
+	for _, numeric := range numericOptions {
+		value := query.Get(numeric.option)
+		if value == "" {
+			continue
+		}
+		predicate, err := newComparison(numeric.field, numeric.op, value)
+		if err != nil {
+			return nil, newInvalidFilterRequestError(numeric.option, value, err)
+		}
+		filter = append(filter, predicate)
+	}
+
+	if host := query.Get(hostFilterOption); host != "" {
+		filter = append(filter, func(urlStat *types.UrlStat) bool {
+			return hostMatches(urlStat, host)
+		})
+	}
+	if prefix := query.Get(urlPrefixFilterOption); prefix != "" {
+		filter = append(filter, func(urlStat *types.UrlStat) bool {
+			return strings.HasPrefix(urlStat.Url, prefix) || strings.HasPrefix(getUrlKey(urlStat), prefix)
+		})
+	}
+	if pattern := query.Get(urlMatchFilterOption); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, newInvalidFilterRequestError(urlMatchFilterOption, pattern, err)
+		}
+		filter = append(filter, func(urlStat *types.UrlStat) bool {
+			return re.MatchString(getUrlKey(urlStat))
+		})
+	}
+	if expression := query.Get(expressionFilterOption); expression != "" {
+		predicate, err := parseFilterExpression(expression)
+		if err != nil {
+			return nil, newInvalidFilterRequestError(expressionFilterOption, expression, err)
+		}
+		filter = append(filter, predicate)
+	}
+	return filter, nil
+}
+
+func newInvalidFilterRequestError(option, value string, err error) error {
+	return &invalidRequestError{
+		code: errCodeInvalidFilter,
+		err:  &invalidFilterError{option: option, value: value, err: err},
+	}
+}
+
+// hostMatches matches the host of the normalized url against the given domain and its subdomains
+func hostMatches(urlStat *types.UrlStat, domain string) bool {
+	u, err := url.Parse(getUrlKey(urlStat))
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	domain = strings.ToLower(domain)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// newComparison compares a record field against a constant value.
+// relevanceScore is compared as float32, the precision it is stored with.
+func newComparison(field, op, value string) (urlStatPredicate, error) {
+	switch field {
+	case viewsOption:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects a number", field)
+		}
+		return compareNumbers(op, func(urlStat *types.UrlStat) float64 { return float64(urlStat.Views) }, v)
+	case relevancescoreOption:
+		v, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects a number", field)
+		}
+		return compareNumbers(op, func(urlStat *types.UrlStat) float64 { return float64(urlStat.RelevanceScore) }, float64(float32(v)))
+	case urlOption:
+		return compareStrings(op, value, func(urlStat *types.UrlStat, match func(string) bool) bool {
+			return match(urlStat.Url) || match(getUrlKey(urlStat))
+		})
+	case hostFilterOption:
+		if op == "~" {
+			return nil, fmt.Errorf("operator %q is not supported for %s", op, field)
+		}
+		return compareStrings(op, value, func(urlStat *types.UrlStat, _ func(string) bool) bool {
+			return hostMatches(urlStat, value)
+		})
+	default:
+		return nil, fmt.Errorf("unknown field %q. Valid fields: %s, %s, %s, %s",
+			field, viewsOption, relevancescoreOption, urlOption, hostFilterOption)
+	}
+}
+
+func compareNumbers(op string, get func(*types.UrlStat) float64, v float64) (urlStatPredicate, error) {
+	switch op {
+	case ">":
+		return func(urlStat *types.UrlStat) bool { return get(urlStat) > v }, nil
+	case ">=":
+		return func(urlStat *types.UrlStat) bool { return get(urlStat) >= v }, nil
+	case "<":
+		return func(urlStat *types.UrlStat) bool { return get(urlStat) < v }, nil
+	case "<=":
+		return func(urlStat *types.UrlStat) bool { return get(urlStat) <= v }, nil
+	case "=", "==":
+		return func(urlStat *types.UrlStat) bool { return get(urlStat) == v }, nil
+	case "!=":
+		return func(urlStat *types.UrlStat) bool { return get(urlStat) != v }, nil
+	default:
+		return nil, fmt.Errorf("operator %q is not supported for numbers", op)
+	}
+}
+
+// compareStrings supports equality and "~", a regular expression match. matchAny
+// reports whether the given matcher accepts any of the record values.
+func compareStrings(op, value string, matchAny func(*types.UrlStat, func(string) bool) bool) (urlStatPredicate, error) {
+	switch op {
+	case "=", "==":
+		return func(urlStat *types.UrlStat) bool {
+			return matchAny(urlStat, func(s string) bool { return s == value })
+		}, nil
+	case "!=":
+		return func(urlStat *types.UrlStat) bool {
+			return !matchAny(urlStat, func(s string) bool { return s == value })
+		}, nil
+	case "~":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(urlStat *types.UrlStat) bool {
+			return matchAny(urlStat, re.MatchString)
+		}, nil
+	default:
+		return nil, fmt.Errorf("operator %q is not supported for strings", op)
+	}
+}
+
+// Filter expressions follow this grammar, with case-insensitive keywords:
+//
+//	expression = term { "or" term }
+//	term       = factor { "and" factor }
+//	factor     = "not" factor | "(" expression ")" | field operator value
+//	operator   = ">" | ">=" | "<" | "<=" | "=" | "==" | "!=" | "~"
+//	value      = number | word | quoted string
+func parseFilterExpression(expression string) (urlStatPredicate, error) {
+	tokens, err := tokenizeFilterExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	predicate, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, tok.errorf("unexpected %q", tok.value)
+	}
+	return predicate, nil
+}
+
+type filterTokenKind int
+
+const (
+	tokenEOF filterTokenKind = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+type filterToken struct {
+	kind  filterTokenKind
+	value string
+	pos   int
+}
+
+func (t filterToken) errorf(format string, a ...any) error {
+	return fmt.Errorf("position %d: %s", t.pos+1, fmt.Sprintf(format, a...))
+}
+
+func (t filterToken) isKeyword(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.value, keyword)
+}
+
+const filterOperatorChars = "<>=!~"
+
+func tokenizeFilterExpression(expression string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{kind: tokenLParen, value: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{kind: tokenRParen, value: ")", pos: i})
+			i++
+		case r == '\'' || r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("position %d: unterminated string", i+1)
+			}
+			tokens = append(tokens, filterToken{kind: tokenString, value: string(runes[i+1 : end]), pos: i})
+			i = end + 1
+		case strings.ContainsRune(filterOperatorChars, r):
+			end := i + 1
+			for end < len(runes) && strings.ContainsRune(filterOperatorChars, runes[end]) {
+				end++
+			}
+			tokens = append(tokens, filterToken{kind: tokenOperator, value: string(runes[i:end]), pos: i})
+			i = end
+		default:
+			end := i + 1
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune("()'\""+filterOperatorChars, runes[end]) {
+				end++
+			}
+			tokens = append(tokens, filterToken{kind: tokenWord, value: string(runes[i:end]), pos: i})
+			i = end
+		}
+	}
+	return append(tokens, filterToken{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *filterParser) parseExpression() (urlStatPredicate, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("or") {
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(urlStat *types.UrlStat) bool { return l(urlStat) || right(urlStat) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseTerm() (urlStatPredicate, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("and") {
+		p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(urlStat *types.UrlStat) bool { return l(urlStat) && right(urlStat) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseFactor() (urlStatPredicate, error) {
+	tok := p.next()
+	switch {
+	case tok.isKeyword("not"):
+		inner, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return func(urlStat *types.UrlStat) bool { return !inner(urlStat) }, nil
+	case tok.kind == tokenLParen:
+		inner, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, closing.errorf("expected %q", ")")
+		}
+		return inner, nil
+	case tok.kind == tokenWord:
+		op := p.next()
+		if op.kind != tokenOperator {
+			return nil, op.errorf("expected an operator after %q", tok.value)
+		}
+		value := p.next()
+		if value.kind != tokenWord && value.kind != tokenString {
+			return nil, value.errorf("expected a value after %q", op.value)
+		}
+		predicate, err := newComparison(tok.value, op.value, value.value)
+		if err != nil {
+			return nil, tok.errorf("%v", err)
+		}
+		return predicate, nil
+	case tok.kind == tokenEOF:
+		return nil, tok.errorf("unexpected end of expression")
+	default:
+		return nil, tok.errorf("unexpected %q", tok.value)
+	}
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+var testFilterUrlStats = types.UrlStatSlice{
+	{Url: "www.example.com/abc1", NormalizedUrl: "https://www.example.com/abc1", Views: 1000, RelevanceScore: 0.5},
+	{Url: "www.example.com/abc2", NormalizedUrl: "https://www.example.com/abc2", Views: 2000, RelevanceScore: 0.3},
+	{Url: "blog.example.org/post", NormalizedUrl: "https://blog.example.org/post", Views: 3000, RelevanceScore: 0.8},
+	{Url: "www.yahoo.com/abc6", NormalizedUrl: "https://www.yahoo.com/abc6", Views: 500, RelevanceScore: 0.1},
+}
+
+func filteredUrls(items types.UrlStatSlice) []string {
+	urls := []string{}
+	for _, urlStat := range items {
+		urls = append(urls, urlStat.Url)
+	}
+	return urls
+}
+
+func TestParseFilters(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       url.Values
+		expected    []string
+		expectedErr bool
+	}{
+		{
+			name:     "no filter",
+			input:    url.Values{},
+			expected: []string{"www.example.com/abc1", "www.example.com/abc2", "blog.example.org/post", "www.yahoo.com/abc6"},
+		},
+		{
+			name:     "minViews and maxViews",
+			input:    url.Values{minViewsFilterOption: {"1000"}, maxViewsFilterOption: {"2000"}},
+			expected: []string{"www.example.com/abc1", "www.example.com/abc2"},
+		},
+		{
+			name:     "minRelevance and maxRelevance are inclusive",
+			input:    url.Values{minRelevanceFilterOption: {"0.3"}, maxRelevanceFilterOption: {"0.5"}},
+			expected: []string{"www.example.com/abc1", "www.example.com/abc2"},
+		},
+		{
+			name:     "host matches subdomains",
+			input:    url.Values{hostFilterOption: {"Example.com"}},
+			expected: []string{"www.example.com/abc1", "www.example.com/abc2"},
+		},
+		{
+			name:     "urlPrefix on the original url",
+			input:    url.Values{urlPrefixFilterOption: {"blog."}},
+			expected: []string{"blog.example.org/post"},
+		},
+		{
+			name:     "urlPrefix on the normalized url",
+			input:    url.Values{urlPrefixFilterOption: {"https://www.yahoo.com/"}},
+			expected: []string{"www.yahoo.com/abc6"},
+		},
+		{
+			name:     "urlMatch",
+			input:    url.Values{urlMatchFilterOption: {`abc[12]$`}},
+			expected: []string{"www.example.com/abc1", "www.example.com/abc2"},
+		},
+		{
+			name:     "query parameters combined with filter expression",
+			input:    url.Values{hostFilterOption: {"example.com"}, expressionFilterOption: {"views>1000"}},
+			expected: []string{"www.example.com/abc2"},
+		},
+		{
+			name:        "invalid minViews",
+			input:       url.Values{minViewsFilterOption: {"many"}},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid urlMatch",
+			input:       url.Values{urlMatchFilterOption: {"abc["}},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid filter expression",
+			input:       url.Values{expressionFilterOption: {"views >"}},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			filter, resultErr := parseFilters(tc.input)
+			assertErr := resultErr != nil
+			if assertErr != tc.expectedErr {
+				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
+					tc.name, tc.expectedErr, resultErr)
+			}
+			if tc.expectedErr {
+				if code := newErrorHandlerResponse(resultErr).Code; code != errCodeInvalidFilter {
+					t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+						tc.name, errCodeInvalidFilter, code)
+				}
+				return
+			}
+			result := filteredUrls(filter.apply(testFilterUrlStats))
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseFilterExpression(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       string
+		expected    []string
+		expectedErr bool
+	}{
+		{
+			name:     "and",
+			input:    "views>1000 and relevanceScore>=0.5",
+			expected: []string{"blog.example.org/post"},
+		},
+		{
+			name:     "or, case-insensitive keywords",
+			input:    "views < 1000 OR relevanceScore = 0.3",
+			expected: []string{"www.example.com/abc2", "www.yahoo.com/abc6"},
+		},
+		{
+			name:     "and binds tighter than or",
+			input:    "views=500 or views>=2000 and relevanceScore<0.5",
+			expected: []string{"www.example.com/abc2", "www.yahoo.com/abc6"},
+		},
+		{
+			name:     "parentheses and not",
+			input:    "not (host = example.com or host = 'yahoo.com')",
+			expected: []string{"blog.example.org/post"},
+		},
+		{
+			name:     "url equality on the original url",
+			input:    `url == "www.example.com/abc1"`,
+			expected: []string{"www.example.com/abc1"},
+		},
+		{
+			name:     "url inequality",
+			input:    "url != https://www.example.com/abc1",
+			expected: []string{"www.example.com/abc2", "blog.example.org/post", "www.yahoo.com/abc6"},
+		},
+		{
+			name:     "url regular expression",
+			input:    "url ~ '^https://www\\.'",
+			expected: []string{"www.example.com/abc1", "www.example.com/abc2", "www.yahoo.com/abc6"},
+		},
+		{
+			name:        "unknown field",
+			input:       "clicks > 1",
+			expectedErr: true,
+		},
+		{
+			name:        "number expected",
+			input:       "views > many",
+			expectedErr: true,
+		},
+		{
+			name:        "unsupported operator for numbers",
+			input:       "views ~ 1",
+			expectedErr: true,
+		},
+		{
+			name:        "unsupported operator",
+			input:       "views => 1",
+			expectedErr: true,
+		},
+		{
+			name:        "missing operator",
+			input:       "views 1000",
+			expectedErr: true,
+		},
+		{
+			name:        "missing closing parenthesis",
+			input:       "(views > 1000",
+			expectedErr: true,
+		},
+		{
+			name:        "trailing tokens",
+			input:       "views > 1000 relevanceScore > 0.1",
+			expectedErr: true,
+		},
+		{
+			name:        "unterminated string",
+			input:       "url = 'www.example.com",
+			expectedErr: true,
+		},
+		{
+			name:        "dangling and",
+			input:       "views > 1000 and",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			predicate, resultErr := parseFilterExpression(tc.input)
+			assertErr := resultErr != nil
+			if assertErr != tc.expectedErr {
+				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
+					tc.name, tc.expectedErr, resultErr)
+			}
+			if tc.expectedErr {
+				return
+			}
+			result := filteredUrls(urlStatFilter{predicate}.apply(testFilterUrlStats))
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+const (
+	healthzPath = "/healthz"
+	readyzPath  = "/readyz"
+
+	healthStatusOk       = "ok"
+	healthStatusReady    = "ready"
+	healthStatusNotReady = "not_ready"
+
+	defaultMaxSnapshotAge = 5 * time.Minute
+)
+
+// serviceHealth is reported by services keeping snapshots, such as cachingService
+type serviceHealth struct {
+	snapshot *types.Snapshot
+	lastErr  error
+	sources  []*types.SourceStatus
+}
+
+// handleHealthz reports liveness. It never touches the Data Sources.
+func (s *apiServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, &types.HealthStatus{Status: healthStatusOk})
+}
+
+// handleReadyz reports readiness: the server is not shutting down, the first data load
+// succeeded and the snapshot being served is not older than the configured maximum age
+func (s *apiServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	status := s.getReadiness()
+	if status.Status != healthStatusReady {
+		writeJson(w, http.StatusServiceUnavailable, status)
+		return
+	}
+	writeJson(w, http.StatusOK, status)
+}
+
+func (s *apiServer) getReadiness() *types.HealthStatus {
+	if s.draining.Load() {
+		return &types.HealthStatus{Status: healthStatusNotReady, Reason: "the server is shutting down"}
+	}
+	if s.snapshots == nil {
+		return &types.HealthStatus{Status: healthStatusNotReady, Reason: "the service keeps no snapshots"}
+	}
+
+	status := &types.HealthStatus{Status: healthStatusReady}
+	health := s.snapshots.getHealth()
+	status.Snapshot = health.snapshot
+	status.Sources = health.sources
+	if health.lastErr != nil {
+		status.LastError = health.lastErr.Error()
+	}
+
+	maxSnapshotAge := durationOrDefault(s.maxSnapshotAge, defaultMaxSnapshotAge)
+	switch {
+	case health.snapshot == nil:
+		status.Status = healthStatusNotReady
+		status.Reason = "no data has been loaded yet"
+	case health.snapshot.AgeSeconds > maxSnapshotAge.Seconds():
+		status.Status = healthStatusNotReady
+		status.Reason = fmt.Sprintf("the snapshot is older than %s", maxSnapshotAge)
+	}
+	return status
+}
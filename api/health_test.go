@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+type healthStubService struct {
+	stubService
+	health serviceHealth
+}
+
+func (s *healthStubService) getHealth() serviceHealth {
+	return s.health
+}
+
+func (s *healthStubService) getUrlStatsDataVersion(ctx context.Context, version uint64) (*types.UrlStatData, error) {
+	return s.getUrlStatsData(ctx)
+}
+
+func (s *healthStubService) getSnapshotStats() (snapshotStats, bool) {
+	return snapshotStats{}, false
+}
+
+func (s *healthStubService) stop() {}
+
+func TestHandleHealthz(t *testing.T) {
+	apiServer := NewApiServer(&healthStubService{})
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		rec := httptest.NewRecorder()
+		apiServer.ServeHTTP(rec, httptest.NewRequest(method, healthzPath, nil))
+
+		expectedStatusCode := http.StatusOK
+		if method != http.MethodGet {
+			expectedStatusCode = http.StatusMethodNotAllowed
+		}
+		if rec.Code != expectedStatusCode {
+			t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v", method, expectedStatusCode, rec.Code)
+		}
+	}
+}
+
+func TestHandleReadyz(t *testing.T) {
+	sources := []*types.SourceStatus{
+		{Source: "http://example.com/a.json", Success: true, Records: 3},
+		{Source: "http://example.com/b.json", Success: false, Error: "HTTP Get to http://example.com/b.json Failed"},
+	}
+
+	testCases := []struct {
+		name               string
+		inputSvc           service
+		inputMaxAge        time.Duration
+		expectedStatusCode int
+		expected           *types.HealthStatus
+	}{
+		{
+			name:               "service without snapshots",
+			inputSvc:           &stubService{data: newStubUrlStatData()},
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expected:           &types.HealthStatus{Status: healthStatusNotReady, Reason: "the service keeps no snapshots"},
+		},
+		{
+			name: "snapshots behind a decorator",
+			inputSvc: NewLoggingService(&healthStubService{health: serviceHealth{
+				snapshot: &types.Snapshot{Version: 2, AgeSeconds: 10},
+			}}, 0),
+			expectedStatusCode: http.StatusOK,
+			expected: &types.HealthStatus{
+				Status:   healthStatusReady,
+				Snapshot: &types.Snapshot{Version: 2, AgeSeconds: 10},
+			},
+		},
+		{
+			name: "no data loaded yet",
+			inputSvc: &healthStubService{health: serviceHealth{
+				lastErr: &upstreamUnavailableError{},
+				sources: sources,
+			}},
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expected: &types.HealthStatus{
+				Status:    healthStatusNotReady,
+				Reason:    "no data has been loaded yet",
+				LastError: (&upstreamUnavailableError{}).Error(),
+				Sources:   sources,
+			},
+		},
+		{
+			name: "fresh snapshot",
+			inputSvc: &healthStubService{health: serviceHealth{
+				snapshot: &types.Snapshot{Version: 2, AgeSeconds: 10},
+				sources:  sources,
+			}},
+			expectedStatusCode: http.StatusOK,
+			expected: &types.HealthStatus{
+				Status:   healthStatusReady,
+				Snapshot: &types.Snapshot{Version: 2, AgeSeconds: 10},
+				Sources:  sources,
+			},
+		},
+		{
+			name: "snapshot older than the default maximum age",
+			inputSvc: &healthStubService{health: serviceHealth{
+				snapshot: &types.Snapshot{Version: 2, AgeSeconds: 600, Stale: true},
+				lastErr:  &upstreamUnavailableError{},
+			}},
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expected: &types.HealthStatus{
+				Status:    healthStatusNotReady,
+				Reason:    "the snapshot is older than 5m0s",
+				Snapshot:  &types.Snapshot{Version: 2, AgeSeconds: 600, Stale: true},
+				LastError: (&upstreamUnavailableError{}).Error(),
+			},
+		},
+		{
+			name: "snapshot older than the configured maximum age",
+			inputSvc: &healthStubService{health: serviceHealth{
+				snapshot: &types.Snapshot{Version: 2, AgeSeconds: 90},
+			}},
+			inputMaxAge:        time.Minute,
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expected: &types.HealthStatus{
+				Status:   healthStatusNotReady,
+				Reason:   "the snapshot is older than 1m0s",
+				Snapshot: &types.Snapshot{Version: 2, AgeSeconds: 90},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			apiServer := NewApiServer(tc.inputSvc, WithMaxSnapshotAge(tc.inputMaxAge))
+			rec := httptest.NewRecorder()
+			apiServer.handleReadyz(rec, httptest.NewRequest(http.MethodGet, readyzPath, nil))
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expectedStatusCode, rec.Code)
+			}
+			result := new(types.HealthStatus)
+			if err := json.NewDecoder(rec.Body).Decode(result); err != nil {
+				t.Fatalf("Internal Testing error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %+v Actual Result: %+v",
+					tc.name, tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestCachingService_Health(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sources := []*types.SourceStatus{{Source: "http://example.com/a.json", Error: "unavailable"}}
+	stub := &stubService{err: &upstreamUnavailableError{sources: sources}}
+	c := &cachingService{next: stub, refreshInterval: time.Hour}
+
+	if err := c.refresh(ctx); err == nil {
+		t.Fatalf("Test Failed. Expected Error to occur: true. Returned Error: %v", err)
+	}
+	health := c.getHealth()
+	if health.snapshot != nil || health.lastErr == nil || !reflect.DeepEqual(health.sources, sources) {
+		t.Fatalf("Test Failed. Unexpected health after a failed first load: %+v", health)
+	}
+
+	loaded := newStubUrlStatData()
+	loaded.Sources = []*types.SourceStatus{{Source: "http://example.com/a.json", Success: true, Records: 1}}
+	stub.setErr(nil)
+	stub.data = loaded
+	if err := c.refresh(ctx); err != nil {
+		t.Fatalf("Test Failed. Unexpected Error: %v", err)
+	}
+	health = c.getHealth()
+	if health.snapshot == nil || health.lastErr != nil || !reflect.DeepEqual(health.sources, loaded.Sources) {
+		t.Fatalf("Test Failed. Unexpected health after a successful load: %+v", health)
+	}
+}
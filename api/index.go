@@ -0,0 +1,71 @@
+package api
+
+import (
+	"log/slog"
+	"strings"
+	"unsafe"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+// sortedIndexes holds the records of a snapshot sorted once per sort key and direction,
+// keyed by the canonical form of the sort keys, e.g. "views:desc,url:asc"
+type sortedIndexes map[string]types.UrlStatSlice
+
+// buildSortedIndexes sorts the records by every supported single sort key, in both directions.
+// Keys failing to sort are left out, so requests for them fall back to sorting per request.
+func buildSortedIndexes(items types.UrlStatSlice) sortedIndexes {
+	indexes := make(sortedIndexes, 2*len(validSortOptions))
+	for _, option := range validSortOptions {
+		for _, descending := range []bool{false, true} {
+			keys := sortKeys{{option: option, descending: descending}}.withTieBreaker()
+			sorted, err := sortByKeys(&items, keys)
+			if err != nil {
+				slog.Warn("Failed to build sorted index", "sortKeys", keys.String(), "error", err)
+				continue
+			}
+			indexes[keys.String()] = *sorted
+		}
+	}
+	return indexes
+}
+
+func (indexes sortedIndexes) lookup(keys sortKeys) (types.UrlStatSlice, bool) {
+	sorted, ok := indexes[keys.String()]
+	return sorted, ok
+}
+
+// sortUrlStats filters the pre-sorted index matching the sort keys, if there is one, which keeps
+// it sorted. Otherwise the filtered records are sorted as far as the page needs, which may
+// leave out the records past the page. total is the number of filtered records either way.
+func sortUrlStats(urlStats *types.UrlStatData, keys sortKeys, filter urlStatFilter, page pageRequest) (sorted *types.UrlStatSlice, total int, err error) {
+	if indexed, ok := sortedIndexes(urlStats.SortedIndexes).lookup(keys); ok {
+		filtered := filter.apply(indexed)
+		return &filtered, len(filtered), nil
+	}
+	filtered := filter.apply(urlStats.Data)
+	sorted, err = sortForPage(&filtered, keys, page)
+	return sorted, len(filtered), err
+}
+
+// sizeBytes is the memory held by the indexes themselves. The records are shared with the snapshot.
+func (indexes sortedIndexes) sizeBytes() int {
+	var size int
+	for _, sorted := range indexes {
+		size += int(unsafe.Sizeof(sorted)) + cap(sorted)*int(unsafe.Sizeof((*types.UrlStat)(nil)))
+	}
+	return size
+}
+
+// String returns the canonical form of the sort keys, with explicit directions
+func (keys sortKeys) String() string {
+	segments := make([]string, len(keys))
+	for i, key := range keys {
+		direction := sortDirectionAsc
+		if key.descending {
+			direction = sortDirectionDesc
+		}
+		segments[i] = key.option + sortDirectionSeparator + direction
+	}
+	return strings.Join(segments, sortKeySeparator)
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+func TestBuildSortedIndexes(t *testing.T) {
+	input := newRandomUrlStats(300, 11, 7)
+	indexes := buildSortedIndexes(input)
+
+	if len(indexes) != 2*len(validSortOptions) {
+		t.Fatalf("Test Failed. Expected Result: %v indexes Actual Result: %v indexes",
+			2*len(validSortOptions), len(indexes))
+	}
+	for _, option := range validSortOptions {
+		for _, direction := range []string{sortDirectionAsc, sortDirectionDesc} {
+			spec := option + sortDirectionSeparator + direction
+			keys, err := parseSortKeys(spec, true)
+			if err != nil {
+				t.Fatalf("Test Failed: %v. Unexpected Error: %v", spec, err)
+			}
+			items := append(types.UrlStatSlice{}, input...)
+			expected, err := sortByKeys(&items, keys)
+			if err != nil {
+				t.Fatalf("Test Failed: %v. Unexpected Error: %v", spec, err)
+			}
+			result, ok := indexes.lookup(keys)
+			if !ok {
+				t.Fatalf("Test Failed: %v. Expected the index to exist", spec)
+			}
+			if !reflect.DeepEqual(result, *expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v", spec, *expected, result)
+			}
+		}
+	}
+	if size := indexes.sizeBytes(); size < 2*len(validSortOptions)*len(input)*8 {
+		t.Fatalf("Test Failed. Expected at least %v bytes. Actual Result: %v",
+			2*len(validSortOptions)*len(input)*8, size)
+	}
+}
+
+func TestSortUrlStats(t *testing.T) {
+	input := newRandomUrlStats(300, 11, 9)
+	indexed := &types.UrlStatData{Data: input, SortedIndexes: buildSortedIndexes(input)}
+	unindexed := &types.UrlStatData{Data: input}
+
+	testCases := []struct {
+		name       string
+		inputKey   string
+		inputQuery url.Values
+	}{
+		{
+			name:     "indexed key",
+			inputKey: "views:desc",
+		},
+		{
+			name:     "indexed key with explicit tie breaker",
+			inputKey: "relevanceScore,url:asc",
+		},
+		{
+			name:       "indexed key with filter",
+			inputKey:   "relevanceScore:desc",
+			inputQuery: url.Values{"minViews": {"50"}},
+		},
+		{
+			name:       "indexed key with limit",
+			inputKey:   "url:desc",
+			inputQuery: url.Values{"limit": {"5"}},
+		},
+		{
+			name:       "not indexed: multiple keys",
+			inputKey:   "views:desc,relevanceScore",
+			inputQuery: url.Values{"maxRelevance": {"0.5"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			keys, err := parseSortKeys(tc.inputKey, true)
+			if err != nil {
+				t.Fatalf("Test Failed: %v. Unexpected Error: %v", tc.name, err)
+			}
+			filter, err := parseFilters(tc.inputQuery)
+			if err != nil {
+				t.Fatalf("Test Failed: %v. Unexpected Error: %v", tc.name, err)
+			}
+			page, err := parsePageRequest(tc.inputKey, tc.inputQuery)
+			if err != nil {
+				t.Fatalf("Test Failed: %v. Unexpected Error: %v", tc.name, err)
+			}
+
+			expected, expectedTotal, err := sortUrlStats(unindexed, keys, filter, page)
+			if err != nil {
+				t.Fatalf("Test Failed: %v. Unexpected Error: %v", tc.name, err)
+			}
+			result, resultTotal, err := sortUrlStats(indexed, keys, filter, page)
+			if err != nil {
+				t.Fatalf("Test Failed: %v. Unexpected Error: %v", tc.name, err)
+			}
+			if resultTotal != expectedTotal {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v", tc.name, expectedTotal, resultTotal)
+			}
+			expectedPage, _ := paginate(expected, page.offset, page.limit)
+			resultPage, _ := paginate(result, page.offset, page.limit)
+			if !reflect.DeepEqual(resultPage, expectedPage) {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, expectedPage, resultPage)
+			}
+			if !reflect.DeepEqual(indexed.Data, input) {
+				t.Fatalf("Test Failed: %v. The snapshot must not be modified", tc.name)
+			}
+		})
+	}
+}
+
+func TestCachingService_SortedIndexes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stub := &stubService{data: newStubUrlStatData()}
+	svc := NewCachingService(ctx, stub, time.Hour)
+
+	result, err := svc.getUrlStatsData(ctx)
+	if err != nil {
+		t.Fatalf("Test Failed. Unexpected Error: %v", err)
+	}
+	if len(result.SortedIndexes) != 2*len(validSortOptions) {
+		t.Fatalf("Test Failed. Expected Result: %v indexes Actual Result: %v indexes",
+			2*len(validSortOptions), len(result.SortedIndexes))
+	}
+	if stub.data.SortedIndexes != nil {
+		t.Fatalf("Test Failed. The data returned by the next service must not be modified")
+	}
+	if size := svc.(*cachingService).sortedIndexBytes(); size <= 0 {
+		t.Fatalf("Test Failed. Expected the sorted index memory to be reported. Actual Result: %v", size)
+	}
+}
+
+func BenchmarkBuildSortedIndexes(b *testing.B) {
+	for _, n := range []int{10000, 1000000} {
+		input := newRandomUrlStats(n, 0, 1)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buildSortedIndexes(input)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+const metricsPath = "/metrics"
+
+// serverMetrics are the metrics exposed on /metrics. Services and servers record
+// into defaultMetrics unless a test sets its own.
+type serverMetrics struct {
+	registry *metricsRegistry
+
+	requests        *metricVec
+	requestDuration *metricVec
+
+	upstreamFetches       *metricVec
+	upstreamFetchErrors   *metricVec
+	upstreamRetries       *metricVec
+	upstreamFetchDuration *metricVec
+	upstreamRecords       *metricVec
+}
+
+var defaultMetrics = newServerMetrics()
+
+func newServerMetrics() *serverMetrics {
+	m := &serverMetrics{
+		registry: &metricsRegistry{},
+		requests: newCounterVec("sortkey_http_requests_total",
+			"HTTP requests served, by route, method and status code.", "route", "method", "code"),
+		requestDuration: newHistogramVec("sortkey_http_request_duration_seconds",
+			"HTTP request latency, by route, method and status code.", defaultDurationBuckets, "route", "method", "code"),
+		upstreamFetches: newCounterVec("sortkey_upstream_fetches_total",
+			"Fetches of each Data Source Endpoint or file.", "source"),
+		upstreamFetchErrors: newCounterVec("sortkey_upstream_fetch_errors_total",
+			"Failed fetches of each Data Source Endpoint or file, after retries.", "source"),
+		upstreamRetries: newCounterVec("sortkey_upstream_retries_total",
+			"Retried attempts against each Data Source Endpoint.", "source"),
+		upstreamFetchDuration: newHistogramVec("sortkey_upstream_fetch_duration_seconds",
+			"Latency of each Data Source Endpoint fetch, including retries.", defaultDurationBuckets, "source"),
+		upstreamRecords: newGaugeVec("sortkey_upstream_records",
+			"Records loaded from each Data Source Endpoint or file by the last fetch.", "source"),
+	}
+	for _, c := range []collector{
+		m.requests, m.requestDuration,
+		m.upstreamFetches, m.upstreamFetchErrors, m.upstreamRetries, m.upstreamFetchDuration, m.upstreamRecords,
+	} {
+		m.registry.register(c)
+	}
+	return m
+}
+
+func (uS *urlStatDataService) getMetrics() *serverMetrics {
+	if uS.metrics == nil {
+		return defaultMetrics
+	}
+	return uS.metrics
+}
+
+func (m *serverMetrics) observeSource(status *types.SourceStatus) {
+	m.upstreamFetches.inc(status.Source)
+	if !status.Success {
+		m.upstreamFetchErrors.inc(status.Source)
+	}
+	m.upstreamRetries.add(float64(status.Retries), status.Source)
+	m.upstreamFetchDuration.observe(status.DurationMs/1000, status.Source)
+	m.upstreamRecords.set(float64(status.Records), status.Source)
+}
+
+func (m *serverMetrics) observeRequest(route, method string, statusCode int, took time.Duration) {
+	code := strconv.Itoa(statusCode)
+	m.requests.inc(route, method, code)
+	m.requestDuration.observe(took.Seconds(), route, method, code)
+}
+
+// statusRecorder captures the status code written by the wrapped handler
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	if r.statusCode == 0 {
+		r.statusCode = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the flusher of the wrapped ResponseWriter
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// instrumentHandler records the request count and latency under the route pattern,
+// so the labels do not grow with the request paths
+func (s *apiServer) instrumentHandler(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w}
+		defer func(start time.Time) {
+			statusCode := recorder.statusCode
+			if statusCode == 0 {
+				statusCode = http.StatusOK
+			}
+			s.metrics.observeRequest(route, getMethodLabel(r.Method), statusCode, time.Since(start))
+		}(time.Now())
+		next(recorder, r)
+	}
+}
+
+// otherMethod labels the requests using methods outside of the standard ones, so clients can not
+// grow the number of series by making methods up
+const otherMethod = "other"
+
+func getMethodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return otherMethod
+}
+
+// snapshotStats describes the snapshots kept by services such as cachingService
+type snapshotStats struct {
+	version          uint64
+	ageSeconds       float64
+	snapshots        int
+	sortedIndexBytes int
+}
+
+func (s *apiServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	var extra []collector
+	if s.snapshots != nil {
+		if stats, ok := s.snapshots.getSnapshotStats(); ok {
+			extra = newSnapshotCollectors(stats)
+		}
+	}
+	w.Header().Set("Content-Type", contentTypeMetrics)
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	_ = s.metrics.registry.write(w, extra...)
+}
+
+func newSnapshotCollectors(stats snapshotStats) []collector {
+	age := newGaugeVec("sortkey_snapshot_age_seconds", "Time since the current data snapshot was loaded.")
+	age.set(stats.ageSeconds)
+	version := newGaugeVec("sortkey_snapshot_version", "Version of the current data snapshot.")
+	version.set(float64(stats.version))
+	snapshots := newGaugeVec("sortkey_snapshots", "Data snapshots kept for cursor pagination.")
+	snapshots.set(float64(stats.snapshots))
+	indexBytes := newGaugeVec("sortkey_sorted_index_bytes", "Memory held by the sorted indexes of every kept snapshot.")
+	indexBytes.set(float64(stats.sortedIndexBytes))
+	return []collector{age, version, snapshots, indexBytes}
+}
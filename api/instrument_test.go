@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+// newMetricsTestServer serves the routes of a server recording into fresh metrics
+func newMetricsTestServer(svc service) (*httptest.Server, *apiServer) {
+	apiServer := NewApiServer(svc)
+	apiServer.metrics = newServerMetrics()
+	return httptest.NewServer(apiServer), apiServer
+}
+
+func scrapeMetrics(t *testing.T, serverUrl string) string {
+	t.Helper()
+	resp, err := http.Get(serverUrl + metricsPath)
+	if err != nil {
+		t.Fatalf("Internal Testing error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", http.StatusOK, resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != contentTypeMetrics {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", contentTypeMetrics, contentType)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Internal Testing error: %v", err)
+	}
+	return string(body)
+}
+
+func TestHandleMetrics_Requests(t *testing.T) {
+	s, _ := newMetricsTestServer(&stubService{data: newStubUrlStatData()})
+	defer s.Close()
+
+	for _, path := range []string{"/sortkey/views", "/sortkey/views", "/sortkey/invalid", "/sortkey/invalid/path", "/"} {
+		resp, err := http.Get(s.URL + path)
+		if err != nil {
+			t.Fatalf("Internal Testing error: %v", err)
+		}
+		resp.Body.Close()
+	}
+	resp, err := http.Post(s.URL+"/sortkey/views", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Internal Testing error: %v", err)
+	}
+	resp.Body.Close()
+	for _, method := range []string{"FOO0", "FOO1"} {
+		req, err := http.NewRequest(method, s.URL+"/sortkey/views", nil)
+		if err != nil {
+			t.Fatalf("Internal Testing error: %v", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Internal Testing error: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	body := scrapeMetrics(t, s.URL)
+	for _, expected := range []string{
+		`sortkey_http_requests_total{route="/sortkey/{key}",method="GET",code="200"} 2`,
+		`sortkey_http_requests_total{route="/sortkey/{key}",method="GET",code="400"} 1`,
+		`sortkey_http_requests_total{route="unmatched",method="GET",code="404"} 1`,
+		`sortkey_http_requests_total{route="unmatched",method="POST",code="405"} 1`,
+		`sortkey_http_requests_total{route="unmatched",method="other",code="405"} 2`,
+		`sortkey_http_requests_total{route="/",method="GET",code="200"} 1`,
+		`sortkey_http_request_duration_seconds_count{route="/sortkey/{key}",method="GET",code="200"} 2`,
+		`sortkey_http_request_duration_seconds_bucket{route="/sortkey/{key}",method="GET",code="200",le="+Inf"} 2`,
+		"# TYPE sortkey_upstream_fetches_total counter",
+	} {
+		if !strings.Contains(body, expected+"\n") {
+			t.Fatalf("Test Failed. Expected the scrape to contain: %v\nActual Result:\n%v", expected, body)
+		}
+	}
+	if strings.Contains(body, `method="FOO`) {
+		t.Fatalf("Test Failed. Expected non-standard methods to be labelled %q. Actual Result:\n%v", otherMethod, body)
+	}
+	if strings.Contains(body, "sortkey_snapshot_age_seconds") {
+		t.Fatalf("Test Failed. Snapshot metrics are only exposed for cached services. Actual Result:\n%v", body)
+	}
+}
+
+func TestHandleMetrics_Upstreams(t *testing.T) {
+	okServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(newStubUrlStatData())
+	}))
+	defer okServer.Close()
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failingServer.Close()
+
+	s, apiServer := newMetricsTestServer(&stubService{data: newStubUrlStatData()})
+	defer s.Close()
+
+	urlStatService := urlStatDataService{
+		dataSourceType: urlDataSourceHttp,
+		retryPolicy: &RetryPolicy{
+			MaxAttempts:          3,
+			BaseDelay:            time.Millisecond,
+			RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+		},
+		metrics: apiServer.metrics,
+	}
+	okUrl, failingUrl := okServer.URL+"/test.json", failingServer.URL+"/test.json"
+	urlStatService.getUrlStatsDataHttpWithStatus(context.Background(), okUrl)
+	urlStatService.getUrlStatsDataHttpWithStatus(context.Background(), okUrl)
+	urlStatService.getUrlStatsDataHttpWithStatus(context.Background(), failingUrl)
+
+	body := scrapeMetrics(t, s.URL)
+	for _, expected := range []string{
+		`sortkey_upstream_fetches_total{source="` + okUrl + `"} 2`,
+		`sortkey_upstream_fetches_total{source="` + failingUrl + `"} 1`,
+		`sortkey_upstream_fetch_errors_total{source="` + failingUrl + `"} 1`,
+		`sortkey_upstream_retries_total{source="` + okUrl + `"} 0`,
+		`sortkey_upstream_retries_total{source="` + failingUrl + `"} 2`,
+		`sortkey_upstream_fetch_duration_seconds_count{source="` + okUrl + `"} 2`,
+		`sortkey_upstream_records{source="` + okUrl + `"} 1`,
+		`sortkey_upstream_records{source="` + failingUrl + `"} 0`,
+	} {
+		if !strings.Contains(body, expected+"\n") {
+			t.Fatalf("Test Failed. Expected the scrape to contain: %v\nActual Result:\n%v", expected, body)
+		}
+	}
+	if strings.Contains(body, `sortkey_upstream_fetch_errors_total{source="`+okUrl+`"}`) {
+		t.Fatalf("Test Failed. Expected no errors for %v. Actual Result:\n%v", okUrl, body)
+	}
+}
+
+func TestHandleMetrics_Snapshot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	svc := NewCachingService(ctx, &stubService{data: newStubUrlStatData()}, time.Hour)
+	if _, err := svc.getUrlStatsData(ctx); err != nil {
+		t.Fatalf("Internal Testing error: %v", err)
+	}
+	s, _ := newMetricsTestServer(svc)
+	defer s.Close()
+
+	body := scrapeMetrics(t, s.URL)
+	for _, expected := range []string{
+		"# TYPE sortkey_snapshot_age_seconds gauge\nsortkey_snapshot_age_seconds ",
+		"# TYPE sortkey_snapshot_version gauge\nsortkey_snapshot_version ",
+		"# TYPE sortkey_snapshots gauge\nsortkey_snapshots ",
+		"# TYPE sortkey_sorted_index_bytes gauge\nsortkey_sorted_index_bytes ",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("Test Failed. Expected the scrape to contain: %v\nActual Result:\n%v", expected, body)
+		}
+	}
+}
+
+func TestHandleMetrics_MethodNotAllowed(t *testing.T) {
+	s, _ := newMetricsTestServer(&stubService{data: &types.UrlStatData{}})
+	defer s.Close()
+
+	resp, err := http.Post(s.URL+metricsPath, "text/plain", nil)
+	if err != nil {
+		t.Fatalf("Internal Testing error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+const (
+	envVarLogLevel         = "LOG_LEVEL"
+	envVarLogFormat        = "LOG_FORMAT"
+	envVarLogPayloadSample = "LOG_PAYLOAD_SAMPLE"
+
+	LogFormatJson = "json"
+	LogFormatText = "text"
+
+	defaultLogPayloadSample = 5
+)
+
+// LoggingConfig controls the structured logs. PayloadSample is the number of records
+// included in debug logs of Data Source payloads, the remaining records are only counted.
+type LoggingConfig struct {
+	Level         slog.Level
+	Format        string
+	PayloadSample int
+}
+
+func DefaultLoggingConfig() LoggingConfig {
+	return LoggingConfig{
+		Level:         slog.LevelInfo,
+		Format:        LogFormatJson,
+		PayloadSample: defaultLogPayloadSample,
+	}
+}
+
+// LoggingConfigFromEnv starts from the default config and applies the LOG_* environment variables
+func LoggingConfigFromEnv(getenv func(string) string) (LoggingConfig, error) {
+	config := DefaultLoggingConfig()
+	if value := getenv(envVarLogLevel); value != "" {
+		if err := config.Level.UnmarshalText([]byte(value)); err != nil {
+			return config, fmt.Errorf("invalid %s: %w", envVarLogLevel, err)
+		}
+	}
+	if value := getenv(envVarLogFormat); value != "" {
+		config.Format = strings.ToLower(value)
+	}
+	if value := getenv(envVarLogPayloadSample); value != "" {
+		var err error
+		if config.PayloadSample, err = strconv.Atoi(value); err != nil {
+			return config, fmt.Errorf("invalid %s: %w", envVarLogPayloadSample, err)
+		}
+	}
+	return config, config.validate()
+}
+
+func (c LoggingConfig) validate() error {
+	if c.Format != LogFormatJson && c.Format != LogFormatText {
+		return fmt.Errorf("logging: format must be %s or %s. Got: %s", LogFormatJson, LogFormatText, c.Format)
+	}
+	if c.PayloadSample < 0 {
+		return fmt.Errorf("logging: payload sample must not be negative. Got: %d", c.PayloadSample)
+	}
+	return nil
+}
+
+// NewLogger returns a logger adding the request ID found in the context of each record
+func NewLogger(w io.Writer, config LoggingConfig) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: config.Level}
+	var handler slog.Handler
+	if config.Format == LogFormatText {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(&contextHandler{Handler: handler})
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if requestId := requestIdFromContext(ctx); requestId != "" {
+		r.AddAttrs(slog.String("requestId", requestId))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
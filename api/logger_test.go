@@ -0,0 +1,289 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+func TestLoggingConfigFromEnv(t *testing.T) {
+	testCases := []struct {
+		name        string
+		inputEnv    map[string]string
+		expected    LoggingConfig
+		expectedErr bool
+	}{
+		{
+			name:     "no env: default config",
+			expected: DefaultLoggingConfig(),
+		},
+		{
+			name: "debug text logs",
+			inputEnv: map[string]string{
+				envVarLogLevel:         "debug",
+				envVarLogFormat:        "TEXT",
+				envVarLogPayloadSample: "0",
+			},
+			expected: LoggingConfig{Level: slog.LevelDebug, Format: LogFormatText, PayloadSample: 0},
+		},
+		{
+			name: "level with offset",
+			inputEnv: map[string]string{
+				envVarLogLevel: "warn+2",
+			},
+			expected: LoggingConfig{Level: slog.LevelWarn + 2, Format: LogFormatJson, PayloadSample: defaultLogPayloadSample},
+		},
+		{
+			name: "invalid level",
+			inputEnv: map[string]string{
+				envVarLogLevel: "verbose",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid format",
+			inputEnv: map[string]string{
+				envVarLogFormat: "xml",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "negative payload sample",
+			inputEnv: map[string]string{
+				envVarLogPayloadSample: "-1",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result, resultErr := LoggingConfigFromEnv(func(key string) string {
+				return tc.inputEnv[key]
+			})
+			assertErr := resultErr != nil
+			if assertErr != tc.expectedErr {
+				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
+					tc.name, tc.expectedErr, resultErr)
+			}
+			if !tc.expectedErr && !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %+v Actual Result: %+v",
+					tc.name, tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestNewLogger_RequestId(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, LoggingConfig{Level: slog.LevelInfo, Format: LogFormatJson})
+
+	ctx := contextWithRequestId(context.Background(), "abc123")
+	logger.With("component", "test").InfoContext(ctx, "with request id")
+	logger.InfoContext(context.Background(), "without request id")
+	logger.DebugContext(ctx, "below level")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Test Failed. Expected Result: 2 log lines Actual Result: %v", lines)
+	}
+	var first, second map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("Test Failed. Expected JSON logs. Error: %v", err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatalf("Test Failed. Expected JSON logs. Error: %v", err)
+	}
+	if first["requestId"] != "abc123" || first["component"] != "test" || first["level"] != "INFO" {
+		t.Fatalf("Test Failed. Unexpected log record: %v", first)
+	}
+	if _, ok := second["requestId"]; ok {
+		t.Fatalf("Test Failed. Unexpected request ID in log record: %v", second)
+	}
+}
+
+func TestMiddlewareHandler_RequestIdContext(t *testing.T) {
+	var contextRequestId string
+	handler := middlewareHandler(func(w http.ResponseWriter, r *http.Request) *handlerResponse {
+		contextRequestId = requestIdFromContext(r.Context())
+		return &handlerResponse{resp: &types.ResponseUrlStats{}, StatusCode: http.StatusOK}
+	})
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if headerRequestId := rec.Header().Get(requestIdHeader); contextRequestId == "" || contextRequestId != headerRequestId {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", headerRequestId, contextRequestId)
+	}
+}
+
+func TestGetUrlStatsDataHttp_RequestIdPropagation(t *testing.T) {
+	received := make(chan string, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(requestIdHeader)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(newStubUrlStatData())
+	}))
+	defer s.Close()
+
+	urlStatService := urlStatDataService{dataSourceType: urlDataSourceHttp}
+	ctx := contextWithRequestId(context.Background(), "abc123")
+	if _, _, err := urlStatService.getUrlStatsDataHttp(ctx, s.URL+"/test.json"); err != nil {
+		t.Fatalf("Test Failed. Unexpected Error: %v", err)
+	}
+	if requestId := <-received; requestId != "abc123" {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", "abc123", requestId)
+	}
+}
+
+// TestLogHandlerResponse replaces the default logger, so it must not run in parallel
+func TestLogHandlerResponse(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(NewLogger(&buf, LoggingConfig{Level: slog.LevelInfo, Format: LogFormatJson}))
+	defer slog.SetDefault(defaultLogger)
+
+	testCases := []struct {
+		name             string
+		inputHandlerResp *handlerResponse
+		expectedLevel    string
+		expectedAttrs    map[string]any
+	}{
+		{
+			name: "success: summary only",
+			inputHandlerResp: &handlerResponse{
+				resp:       &types.ResponseUrlStats{SortedUrlStats: &types.UrlStatSlice{{Url: "www.example.com/abc1"}}, Count: 1, Total: 3},
+				StatusCode: http.StatusOK,
+			},
+			expectedLevel: "INFO",
+			expectedAttrs: map[string]any{"count": float64(1), "total": float64(3), "status": float64(200)},
+		},
+		{
+			name: "client error",
+			inputHandlerResp: &handlerResponse{
+				Err:        &invalidSortKeyError{segment: "unsupported"},
+				StatusCode: http.StatusBadRequest,
+				Code:       errCodeInvalidSortKey,
+			},
+			expectedLevel: "WARN",
+			expectedAttrs: map[string]any{"code": errCodeInvalidSortKey, "status": float64(400)},
+		},
+		{
+			name: "server error",
+			inputHandlerResp: &handlerResponse{
+				Err:        &upstreamUnavailableError{},
+				StatusCode: http.StatusBadGateway,
+				Code:       errCodeUpstreamUnavailable,
+			},
+			expectedLevel: "ERROR",
+			expectedAttrs: map[string]any{"code": errCodeUpstreamUnavailable, "status": float64(502)},
+		},
+	}
+
+	for _, tc := range testCases {
+		buf.Reset()
+		handler := middlewareHandler(func(w http.ResponseWriter, r *http.Request) *handlerResponse {
+			return tc.inputHandlerResp
+		})
+		req := httptest.NewRequest(http.MethodGet, "/sortkey/views", nil)
+		req.Header.Set(requestIdHeader, "abc123")
+		handler(httptest.NewRecorder(), req)
+
+		var record map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if strings.Contains(line, `"msg":"Handled request"`) && strings.Contains(line, "abc123") {
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("Test Failed: %v. Expected JSON logs. Error: %v", tc.name, err)
+				}
+			}
+		}
+		if record == nil {
+			t.Fatalf("Test Failed: %v. Expected a request log. Actual Result: %v", tc.name, buf.String())
+		}
+		if record["level"] != tc.expectedLevel || record["path"] != "/sortkey/views" {
+			t.Fatalf("Test Failed: %v. Unexpected log record: %v", tc.name, record)
+		}
+		for key, expected := range tc.expectedAttrs {
+			if record[key] != expected {
+				t.Fatalf("Test Failed: %v. Expected %v: %v Actual Result: %v", tc.name, key, expected, record[key])
+			}
+		}
+		if strings.Contains(buf.String(), "www.example.com/abc1") {
+			t.Fatalf("Test Failed: %v. The response payload must not be logged: %v", tc.name, buf.String())
+		}
+	}
+}
+
+func TestGetOrCreateRequestId(t *testing.T) {
+	testCases := []struct {
+		name          string
+		inputHeader   string
+		expectedReuse bool
+	}{
+		{
+			name:          "caller provided id reused",
+			inputHeader:   "abc-123_DEF.4",
+			expectedReuse: true,
+		},
+		{
+			name:          "longest id reused",
+			inputHeader:   strings.Repeat("a", maxRequestIdLength),
+			expectedReuse: true,
+		},
+		{
+			name:        "no id: generated",
+			inputHeader: "",
+		},
+		{
+			name:        "too long: generated",
+			inputHeader: strings.Repeat("a", maxRequestIdLength+1),
+		},
+		{
+			name:        "unsafe characters: generated",
+			inputHeader: `abc" level="ERROR`,
+		},
+		{
+			name:        "non ascii: generated",
+			inputHeader: "abcé",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(requestIdHeader, tc.inputHeader)
+			rec := httptest.NewRecorder()
+
+			result := getOrCreateRequestId(rec, req)
+			if (result == tc.inputHeader) != tc.expectedReuse {
+				t.Fatalf("Test Failed: %v. Expected the caller provided id to be reused: %v Actual Result: %q",
+					tc.name, tc.expectedReuse, result)
+			}
+			if !isValidRequestId(result) || rec.Header().Get(requestIdHeader) != result {
+				t.Fatalf("Test Failed: %v. Expected Result: a valid id echoed in the header Actual Result: %q header %q",
+					tc.name, result, rec.Header().Get(requestIdHeader))
+			}
+		})
+	}
+}
+
+func TestSampleUrlStats(t *testing.T) {
+	input := types.UrlStatSlice{{Url: "a"}, {Url: "b"}, {Url: "c"}}
+	if result := sampleUrlStats(input, 2); len(result) != 2 || cap(result) != 2 {
+		t.Fatalf("Test Failed. Expected Result: 2 records Actual Result: %v", result)
+	}
+	if result := sampleUrlStats(input, 5); len(result) != 3 {
+		t.Fatalf("Test Failed. Expected Result: 3 records Actual Result: %v", result)
+	}
+}
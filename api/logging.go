@@ -2,29 +2,48 @@ package api
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"time"
 
 	"github.com/felipe88alves/sortKeyHttpServer/types"
 )
 
 type loggingService struct {
-	next service
+	next          service
+	payloadSample int
 }
 
-func NewLoggingService(next service) service {
+// NewLoggingService logs a summary of every load. At debug level, the first payloadSample records are included.
+func NewLoggingService(next service, payloadSample int) service {
 	return &loggingService{
-		next: next,
+		next:          next,
+		payloadSample: payloadSample,
 	}
 }
 
 func (l *loggingService) getUrlStatsData(ctx context.Context) (data *types.UrlStatData, err error) {
 	defer func(start time.Time) {
-		if data != nil {
-			log.Printf("Input Data:%+v Error:%v took:%v\n", data.Data.String(), err, time.Since(start))
-		} else {
-			log.Printf("Error: %v took:%v\n", err, time.Since(start))
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to load Url Stats Data", "error", err, "took", time.Since(start))
+			return
+		}
+		slog.InfoContext(ctx, "Loaded Url Stats Data",
+			"records", len(data.Data), "sources", len(data.Sources), "took", time.Since(start))
+		if slog.Default().Enabled(ctx, slog.LevelDebug) {
+			slog.DebugContext(ctx, "Url Stats Data sample",
+				"sample", sampleUrlStats(data.Data, l.payloadSample), "records", len(data.Data))
 		}
 	}(time.Now())
 	return l.next.getUrlStatsData(ctx)
 }
+
+func (l *loggingService) unwrapService() service {
+	return l.next
+}
+
+func sampleUrlStats(items types.UrlStatSlice, n int) types.UrlStatSlice {
+	if len(items) <= n {
+		return items
+	}
+	return items[:n:n]
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+const (
+	envVarMergeViews          = "MERGE_VIEWS"
+	envVarMergeRelevanceScore = "MERGE_RELEVANCE_SCORE"
+)
+
+// MergeStrategy controls how a field is computed when the same url is reported more than once
+type MergeStrategy string
+
+const (
+	// MergeFirst keeps the value of the first Data Source, in the configured order
+	MergeFirst MergeStrategy = "first"
+	// MergeSum adds up the values. Only supported for views
+	MergeSum MergeStrategy = "sum"
+	// MergeMax keeps the highest value
+	MergeMax MergeStrategy = "max"
+	// MergeWeighted averages the values, weighted by views. Only supported for relevanceScore
+	MergeWeighted MergeStrategy = "weighted"
+)
+
+// MergeConfig holds the strategy for each field. Empty strategies fall back to MergeFirst
+type MergeConfig struct {
+	Views          MergeStrategy
+	RelevanceScore MergeStrategy
+}
+
+// sourceBatch holds the records loaded from a single Data Source
+type sourceBatch struct {
+	source string
+	data   types.UrlStatSlice
+}
+
+type mergeGroup struct {
+	records []*types.UrlStat
+	sources []string
+}
+
+// mergeDuplicates merges records sharing the same normalized url, keeping the order in which urls were first seen.
+// Null records, which can only be kept by ValidationWarn, carry no url and are dropped.
+func (c MergeConfig) mergeDuplicates(batches []sourceBatch) types.UrlStatSlice {
+	index := make(map[string]int)
+	var groups []*mergeGroup
+
+	for _, batch := range batches {
+		for _, urlStat := range batch.data {
+			if urlStat == nil {
+				continue
+			}
+			key := getUrlKey(urlStat)
+			i, ok := index[key]
+			if !ok {
+				i = len(groups)
+				index[key] = i
+				groups = append(groups, &mergeGroup{})
+			}
+			group := groups[i]
+			group.records = append(group.records, urlStat)
+			if len(group.sources) == 0 || group.sources[len(group.sources)-1] != batch.source {
+				group.sources = append(group.sources, batch.source)
+			}
+		}
+	}
+
+	merged := make(types.UrlStatSlice, 0, len(groups))
+	for _, group := range groups {
+		if len(group.records) == 1 {
+			merged = append(merged, group.records[0])
+			continue
+		}
+		merged = append(merged, c.merge(group))
+	}
+	return merged
+}
+
+// merge returns a new record, so the records of the Data Sources are left untouched
+func (c MergeConfig) merge(group *mergeGroup) *types.UrlStat {
+	first := group.records[0]
+	urlStat := &types.UrlStat{
+		Url:            first.Url,
+		NormalizedUrl:  first.NormalizedUrl,
+		Views:          first.Views,
+		RelevanceScore: first.RelevanceScore,
+		Sources:        group.sources,
+	}
+
+	totalViews := 0
+	var weightedRelevanceScore, totalRelevanceScore float64
+	for _, record := range group.records {
+		totalViews += record.Views
+		weightedRelevanceScore += float64(record.RelevanceScore) * float64(record.Views)
+		totalRelevanceScore += float64(record.RelevanceScore)
+
+		if c.Views == MergeMax && record.Views > urlStat.Views {
+			urlStat.Views = record.Views
+		}
+		if c.RelevanceScore == MergeMax && record.RelevanceScore > urlStat.RelevanceScore {
+			urlStat.RelevanceScore = record.RelevanceScore
+		}
+	}
+
+	if c.Views == MergeSum {
+		urlStat.Views = totalViews
+	}
+	if c.RelevanceScore == MergeWeighted {
+		// Without views to weight by, every record weighs the same
+		if totalViews > 0 {
+			urlStat.RelevanceScore = float32(weightedRelevanceScore / float64(totalViews))
+		} else {
+			urlStat.RelevanceScore = float32(totalRelevanceScore / float64(len(group.records)))
+		}
+	}
+	return urlStat
+}
+
+// MergeConfigFromEnv reads the strategies from MERGE_VIEWS and MERGE_RELEVANCE_SCORE
+func MergeConfigFromEnv(getenv func(string) string) (MergeConfig, error) {
+	config := MergeConfig{
+		Views:          MergeFirst,
+		RelevanceScore: MergeFirst,
+	}
+	if value := getenv(envVarMergeViews); value != "" {
+		config.Views = MergeStrategy(value)
+	}
+	if value := getenv(envVarMergeRelevanceScore); value != "" {
+		config.RelevanceScore = MergeStrategy(value)
+	}
+	return config, config.validate()
+}
+
+func (c MergeConfig) validate() error {
+	switch c.Views {
+	case "", MergeFirst, MergeSum, MergeMax:
+	default:
+		return fmt.Errorf("merge: unsupported strategy %q for %s", c.Views, viewsOption)
+	}
+	switch c.RelevanceScore {
+	case "", MergeFirst, MergeMax, MergeWeighted:
+	default:
+		return fmt.Errorf("merge: unsupported strategy %q for %s", c.RelevanceScore, relevancescoreOption)
+	}
+	return nil
+}
+
+// getUrlKey falls back to Url for records that were not normalized
+func getUrlKey(urlStat *types.UrlStat) string {
+	if urlStat.NormalizedUrl != "" {
+		return urlStat.NormalizedUrl
+	}
+	return urlStat.Url
+}
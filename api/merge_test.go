@@ -0,0 +1,141 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+func TestMergeDuplicates(t *testing.T) {
+	const (
+		sourceA = "a.json"
+		sourceB = "b.json"
+	)
+
+	input := []sourceBatch{
+		{
+			source: sourceA,
+			data: types.UrlStatSlice{
+				{Url: "www.example.com/abc1", Views: 1000, RelevanceScore: 0.2},
+				{Url: "www.example.com/abc2", Views: 2000, RelevanceScore: 0.5},
+				nil,
+			},
+		},
+		{
+			source: sourceB,
+			data: types.UrlStatSlice{
+				{Url: "www.example.com/abc3", Views: 3000, RelevanceScore: 0.3},
+				{Url: "www.example.com/abc1", Views: 3000, RelevanceScore: 0.6},
+			},
+		},
+	}
+	unmerged := []*types.UrlStat{input[0].data[1], input[1].data[0]}
+
+	testCases := []struct {
+		name        string
+		inputConfig MergeConfig
+		expected    *types.UrlStat
+	}{
+		{
+			name:     "default: keep first",
+			expected: &types.UrlStat{Url: "www.example.com/abc1", Views: 1000, RelevanceScore: 0.2, Sources: []string{sourceA, sourceB}},
+		},
+		{
+			name:        "sum views, max relevanceScore",
+			inputConfig: MergeConfig{Views: MergeSum, RelevanceScore: MergeMax},
+			expected:    &types.UrlStat{Url: "www.example.com/abc1", Views: 4000, RelevanceScore: 0.6, Sources: []string{sourceA, sourceB}},
+		},
+		{
+			name:        "max views, weighted relevanceScore",
+			inputConfig: MergeConfig{Views: MergeMax, RelevanceScore: MergeWeighted},
+			expected:    &types.UrlStat{Url: "www.example.com/abc1", Views: 3000, RelevanceScore: 0.5, Sources: []string{sourceA, sourceB}},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result := tc.inputConfig.mergeDuplicates(input)
+			expected := types.UrlStatSlice{tc.expected, unmerged[0], unmerged[1]}
+			if !reflect.DeepEqual(result, expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, expected.String(), result.String())
+			}
+			if input[0].data[0].Views != 1000 || input[0].data[0].Sources != nil {
+				t.Fatalf("Test Failed: %v. Input records must not be modified. Actual Result: %+v",
+					tc.name, *input[0].data[0])
+			}
+		})
+	}
+}
+
+func TestMergeWeighted_NoViews(t *testing.T) {
+	group := &mergeGroup{
+		records: []*types.UrlStat{
+			{Url: "www.example.com/abc1", RelevanceScore: 0.2},
+			{Url: "www.example.com/abc1", RelevanceScore: 0.4},
+		},
+		sources: []string{"a.json"},
+	}
+	result := MergeConfig{RelevanceScore: MergeWeighted}.merge(group)
+	if result.RelevanceScore < 0.2999 || result.RelevanceScore > 0.3001 {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", 0.3, result.RelevanceScore)
+	}
+}
+
+func TestMergeConfigFromEnv(t *testing.T) {
+	testCases := []struct {
+		name        string
+		inputEnv    map[string]string
+		expected    MergeConfig
+		expectedErr bool
+	}{
+		{
+			name:     "no env: keep first",
+			expected: MergeConfig{Views: MergeFirst, RelevanceScore: MergeFirst},
+		},
+		{
+			name: "sum views, weighted relevanceScore",
+			inputEnv: map[string]string{
+				envVarMergeViews:          string(MergeSum),
+				envVarMergeRelevanceScore: string(MergeWeighted),
+			},
+			expected: MergeConfig{Views: MergeSum, RelevanceScore: MergeWeighted},
+		},
+		{
+			name: "weighted views unsupported",
+			inputEnv: map[string]string{
+				envVarMergeViews: string(MergeWeighted),
+			},
+			expectedErr: true,
+		},
+		{
+			name: "sum relevanceScore unsupported",
+			inputEnv: map[string]string{
+				envVarMergeRelevanceScore: string(MergeSum),
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result, resultErr := MergeConfigFromEnv(func(key string) string {
+				return tc.inputEnv[key]
+			})
+			assertErr := resultErr != nil
+			if assertErr != tc.expectedErr {
+				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
+					tc.name, tc.expectedErr, resultErr)
+			}
+			if !tc.expectedErr && !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %+v Actual Result: %+v",
+					tc.name, tc.expected, result)
+			}
+		})
+	}
+}
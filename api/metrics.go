@@ -0,0 +1,225 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// contentTypeMetrics is the Prometheus text exposition format
+const contentTypeMetrics = "text/plain; version=0.0.4; charset=utf-8"
+
+const (
+	metricTypeCounter   = "counter"
+	metricTypeGauge     = "gauge"
+	metricTypeHistogram = "histogram"
+)
+
+// defaultDurationBuckets are the upper bounds, in seconds, of the latency histograms
+var defaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// collector writes one metric family in the Prometheus text exposition format
+type collector interface {
+	writeTo(w *bufio.Writer)
+}
+
+// metricsRegistry writes its collectors in registration order
+type metricsRegistry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+func (r *metricsRegistry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+func (r *metricsRegistry) write(w io.Writer, extra ...collector) error {
+	r.mu.Lock()
+	collectors := append(append([]collector{}, r.collectors...), extra...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.writeTo(bw)
+	}
+	return bw.Flush()
+}
+
+// metricVec is a metric family with one series per combination of label values
+type metricVec struct {
+	name       string
+	help       string
+	metricType string
+	labelNames []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*metricSeries
+}
+
+type metricSeries struct {
+	labelValues []string
+	value       float64
+
+	// Histograms only. bucketCounts are not cumulative.
+	bucketCounts []uint64
+	count        uint64
+}
+
+func newMetricVec(name, help, metricType string, buckets []float64, labelNames ...string) *metricVec {
+	return &metricVec{
+		name:       name,
+		help:       help,
+		metricType: metricType,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     map[string]*metricSeries{},
+	}
+}
+
+func newCounterVec(name, help string, labelNames ...string) *metricVec {
+	return newMetricVec(name, help, metricTypeCounter, nil, labelNames...)
+}
+
+func newGaugeVec(name, help string, labelNames ...string) *metricVec {
+	return newMetricVec(name, help, metricTypeGauge, nil, labelNames...)
+}
+
+func newHistogramVec(name, help string, buckets []float64, labelNames ...string) *metricVec {
+	return newMetricVec(name, help, metricTypeHistogram, buckets, labelNames...)
+}
+
+// getSeries must be called with mu held
+func (m *metricVec) getSeries(labelValues []string) *metricSeries {
+	if len(labelValues) != len(m.labelNames) {
+		panic(fmt.Sprintf("metric %s: expected %d label values, got %d", m.name, len(m.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := m.series[key]
+	if !ok {
+		s = &metricSeries{labelValues: append([]string{}, labelValues...)}
+		if m.metricType == metricTypeHistogram {
+			s.bucketCounts = make([]uint64, len(m.buckets))
+		}
+		m.series[key] = s
+	}
+	return s
+}
+
+func (m *metricVec) add(value float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.getSeries(labelValues).value += value
+}
+
+func (m *metricVec) inc(labelValues ...string) {
+	m.add(1, labelValues...)
+}
+
+func (m *metricVec) set(value float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.getSeries(labelValues).value = value
+}
+
+func (m *metricVec) observe(value float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.getSeries(labelValues)
+	s.value += value
+	s.count++
+	for i, upperBound := range m.buckets {
+		if value <= upperBound {
+			s.bucketCounts[i]++
+			break
+		}
+	}
+}
+
+// get returns the value of a counter or gauge series, or the sum of a histogram series
+func (m *metricVec) get(labelValues ...string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.getSeries(labelValues).value
+}
+
+func (m *metricVec) writeTo(w *bufio.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", m.name, escapeMetricHelp(m.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.metricType)
+
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := m.series[key]
+		if m.metricType != metricTypeHistogram {
+			writeSample(w, m.name, m.labelNames, s.labelValues, s.value)
+			continue
+		}
+		labelNames := append(append([]string{}, m.labelNames...), "le")
+		var cumulative uint64
+		for i, upperBound := range m.buckets {
+			cumulative += s.bucketCounts[i]
+			writeSample(w, m.name+"_bucket", labelNames, append(append([]string{}, s.labelValues...), formatMetricValue(upperBound)), float64(cumulative))
+		}
+		writeSample(w, m.name+"_bucket", labelNames, append(append([]string{}, s.labelValues...), "+Inf"), float64(s.count))
+		writeSample(w, m.name+"_sum", m.labelNames, s.labelValues, s.value)
+		writeSample(w, m.name+"_count", m.labelNames, s.labelValues, float64(s.count))
+	}
+}
+
+func writeSample(w *bufio.Writer, name string, labelNames, labelValues []string, value float64) {
+	w.WriteString(name)
+	if len(labelNames) > 0 {
+		w.WriteByte('{')
+		for i, labelName := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", labelName, escapeLabelValue(labelValues[i]))
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatMetricValue(value))
+	w.WriteByte('\n')
+}
+
+func formatMetricValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+var (
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
+
+func escapeMetricHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMetricsRegistryWrite(t *testing.T) {
+	requests := newCounterVec("test_requests_total", "Requests served.", "route", "code")
+	requests.inc("/b", "200")
+	requests.add(2, "/a", "200")
+	requests.inc("/a", "500")
+
+	records := newGaugeVec("test_records", "Records loaded.\nPer source.", "source")
+	records.set(3, `C:\data "x"`)
+
+	duration := newHistogramVec("test_duration_seconds", "Request latency.", []float64{0.1, 1})
+	duration.observe(0.05)
+	duration.observe(0.5)
+	duration.observe(5)
+
+	empty := newGaugeVec("test_empty", "No series.")
+	age := newGaugeVec("test_age_seconds", "Added at scrape time.")
+	age.set(math.Inf(1))
+
+	registry := &metricsRegistry{}
+	registry.register(requests)
+	registry.register(records)
+	registry.register(duration)
+	registry.register(empty)
+
+	var b strings.Builder
+	if err := registry.write(&b, age); err != nil {
+		t.Fatalf("Test Failed. Unexpected Error: %v", err)
+	}
+
+	expected := `# HELP test_requests_total Requests served.
+# TYPE test_requests_total counter
+test_requests_total{route="/a",code="200"} 2
+test_requests_total{route="/a",code="500"} 1
+test_requests_total{route="/b",code="200"} 1
+# HELP test_records Records loaded.\nPer source.
+# TYPE test_records gauge
+test_records{source="C:\\data \"x\""} 3
+# HELP test_duration_seconds Request latency.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="0.1"} 1
+test_duration_seconds_bucket{le="1"} 2
+test_duration_seconds_bucket{le="+Inf"} 3
+test_duration_seconds_sum 5.55
+test_duration_seconds_count 3
+# HELP test_empty No series.
+# TYPE test_empty gauge
+# HELP test_age_seconds Added at scrape time.
+# TYPE test_age_seconds gauge
+test_age_seconds +Inf
+`
+	if b.String() != expected {
+		t.Fatalf("Test Failed. Expected Result:\n%v\nActual Result:\n%v", expected, b.String())
+	}
+}
+
+func TestMetricVec_LabelCountMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Test Failed. Expected a panic on a label count mismatch")
+		}
+	}()
+	newCounterVec("test_total", "Test.", "route").inc()
+}
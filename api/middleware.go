@@ -2,19 +2,31 @@ package api
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/felipe88alves/sortKeyHttpServer/types"
 )
 
+const (
+	contentTypeJson        = "application/json"
+	contentTypeProblemJson = "application/problem+json"
+
+	problemTypeDefault = "about:blank"
+)
+
 type customHandlerFunc func(w http.ResponseWriter, r *http.Request) *handlerResponse
 
 type handlerResponse struct {
 	resp       *types.ResponseUrlStats
 	Err        error
 	StatusCode int
+	Code       string
+
+	requestId string
+	instance  string
 }
 
 func (e handlerResponse) Error() string {
@@ -25,45 +37,101 @@ func middlewareHandler(f customHandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var handlerResp *handlerResponse
 
+		requestId := getOrCreateRequestId(w, r)
+		r = r.WithContext(contextWithRequestId(r.Context(), requestId))
+
 		defer func(start time.Time) {
 			if handlerResp != nil {
-				logHandlerResponse(handlerResp, start)
+				logHandlerResponse(r, handlerResp, start)
 			}
 		}(time.Now())
 
 		handlerResp = f(w, r)
+		if handlerResp == nil {
+			return
+		}
 
-		if handlerResp != nil {
-			sendHttpResponse(handlerResp, w)
+		encoder := urlStatsEncoders[0]
+		if handlerResp.Err == nil {
+			var err error
+			if encoder, err = negotiateEncoder(r); err != nil {
+				handlerResp = newErrorHandlerResponse(err)
+			}
+		}
+		handlerResp.requestId = requestId
+		handlerResp.instance = r.URL.Path
+		if err := sendHttpResponse(handlerResp, encoder, w, r); err != nil {
+			// The status line is already sent. The client gets a truncated body.
+			slog.WarnContext(r.Context(), "Failed to stream response", "path", r.URL.Path, "error", err)
 		}
 	}
 }
 
-func sendHttpResponse(handlerResp *handlerResponse, w http.ResponseWriter) {
+// sendHttpResponse writes errors as problem+json and streams successful responses using the
+// negotiated encoder, until the client disconnects
+func sendHttpResponse(handlerResp *handlerResponse, encoder urlStatsEncoder, w http.ResponseWriter, r *http.Request) error {
 	if handlerResp.Err != nil {
-		// errMsg := fmt.Errorf("%v %w", handlerResp.StatusCode, handlerResp.Err)
-		writeJson(w, handlerResp.StatusCode, nil)
-	} else {
-		writeJson(w, handlerResp.StatusCode, handlerResp.resp)
+		return writeProblemJson(w, handlerResp.StatusCode, newErrorResponse(handlerResp))
 	}
+	w.Header().Add("Vary", "Accept")
+	writeContentTypeHeader(w, encoder.contentType, handlerResp.StatusCode)
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	return encoder.encode(newStreamWriter(r.Context(), w), handlerResp.resp)
 }
 
-func logHandlerResponse(handlerResp *handlerResponse, start time.Time) {
-	if handlerResp.Err != nil {
-		log.Printf("HTTP Status Code: %d Error: %s Handler took:%v\n",
-			handlerResp.StatusCode, handlerResp.Error(), time.Since(start))
-	} else {
-		log.Printf("HTTP Status Code: %d HTTP Response: %+v Handler took:%v\n",
-			handlerResp.StatusCode, *handlerResp.resp, time.Since(start))
+func newErrorResponse(handlerResp *handlerResponse) *types.ErrorResponse {
+	errResp := &types.ErrorResponse{
+		Type:      problemTypeDefault,
+		Title:     http.StatusText(handlerResp.StatusCode),
+		Status:    handlerResp.StatusCode,
+		Detail:    handlerResp.Error(),
+		Instance:  handlerResp.instance,
+		Code:      getErrorCode(handlerResp),
+		RequestId: handlerResp.requestId,
 	}
+	var sortKeyErr *invalidSortKeyError
+	if errors.As(handlerResp.Err, &sortKeyErr) {
+		errResp.ValidSortKeys = validSortOptions
+	}
+	return errResp
+}
+
+// logHandlerResponse logs a summary of the response. Server errors are logged at error level,
+// client errors at warning level and successful responses at info level.
+func logHandlerResponse(r *http.Request, handlerResp *handlerResponse, start time.Time) {
+	attrs := []any{
+		"method", r.Method,
+		"path", r.URL.Path,
+		"status", handlerResp.StatusCode,
+		"took", time.Since(start),
+	}
+	level := slog.LevelInfo
+	switch {
+	case handlerResp.Err != nil:
+		attrs = append(attrs, "code", getErrorCode(handlerResp), "error", handlerResp.Error())
+		level = slog.LevelWarn
+		if handlerResp.StatusCode >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+	case handlerResp.resp != nil:
+		attrs = append(attrs, "count", handlerResp.resp.Count, "total", handlerResp.resp.Total)
+	}
+	slog.Log(r.Context(), level, "Handled request", attrs...)
 }
 
 func writeJson(w http.ResponseWriter, httpStatus int, v any) error {
-	writeJsonHeader(w, httpStatus)
+	writeContentTypeHeader(w, contentTypeJson, httpStatus)
+	return json.NewEncoder(w).Encode(v)
+}
+
+func writeProblemJson(w http.ResponseWriter, httpStatus int, v any) error {
+	writeContentTypeHeader(w, contentTypeProblemJson, httpStatus)
 	return json.NewEncoder(w).Encode(v)
 }
 
-func writeJsonHeader(w http.ResponseWriter, httpStatus int) {
-	w.Header().Set("Content-Type", "application/json")
+func writeContentTypeHeader(w http.ResponseWriter, contentType string, httpStatus int) {
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(httpStatus)
 }
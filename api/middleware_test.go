@@ -3,13 +3,12 @@ package api
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
-	"log"
 	"net/http"
+	"net/http/httptest"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
-	"time"
 
 	"github.com/felipe88alves/sortKeyHttpServer/types"
 )
@@ -27,8 +26,7 @@ func init() {
 	)
 
 	// Overwritting global params to reduce test time
-	retryAttempts = 1
-	backoffPeriods = []time.Duration{0}
+	defaultRetryPolicy = RetryPolicy{MaxAttempts: 1}
 }
 
 func (s *apiServer) stubHandlerResponseSuccess(w http.ResponseWriter, r *http.Request) *handlerResponse {
@@ -42,17 +40,9 @@ func (s *apiServer) stubHandlerResponseError(w http.ResponseWriter, r *http.Requ
 }
 func TestMiddlewareHandler(t *testing.T) {
 	const (
-		maxAttempts = 5
-		inputPort   = ":6060"
-
 		testApiErrorPath = "/apiError/"
 		testSuccessPath  = "/success/"
 	)
-	var (
-		resp     *http.Response
-		httpConn bool
-		// err      error
-	)
 	testCases := []struct {
 		name                  string
 		inputApiErrorReturned bool
@@ -78,38 +68,18 @@ func TestMiddlewareHandler(t *testing.T) {
 	svc := new(urlStatDataService)
 	apiSvc := NewApiServer(svc)
 
-	http.HandleFunc(testApiErrorPath, middlewareHandler(apiSvc.stubHandlerResponseError))
-	http.HandleFunc(testSuccessPath, middlewareHandler(apiSvc.stubHandlerResponseSuccess))
-
-	go func() {
-		log.Fatal(http.ListenAndServe(inputPort, nil))
-	}()
+	mux := http.NewServeMux()
+	mux.HandleFunc(testApiErrorPath, middlewareHandler(apiSvc.stubHandlerResponseError))
+	mux.HandleFunc(testSuccessPath, middlewareHandler(apiSvc.stubHandlerResponseSuccess))
+	s := httptest.NewServer(mux)
+	defer s.Close()
 
 	for _, tc := range testCases {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-
-			for attempt := 1; attempt <= maxAttempts; attempt++ {
-				req, err := http.NewRequest("GET",
-					fmt.Sprintf("http://localhost%s%s", inputPort, tc.inputTestUrlPath),
-					nil)
-				if err != nil {
-					t.Fatalf("Internal Testing error: %v", err)
-				}
-				req.Close = true
-				client := &http.Client{}
-				resp, err = client.Do(req)
-				if err == nil {
-					defer resp.Body.Close()
-					httpConn = true
-					break
-				}
-
-				time.Sleep(time.Second)
-				t.Logf("Retrying connection to Mock Server (%d/%d)", attempt, maxAttempts)
-			}
-			if !httpConn {
-				t.Fatalf("Failed to connect to Mock Server")
+			resp, err := http.Get(s.URL + tc.inputTestUrlPath)
+			if err != nil {
+				t.Fatalf("Internal Testing error: %v", err)
 			}
 			defer func() {
 				if err := resp.Body.Close(); err != nil {
@@ -129,3 +99,99 @@ func TestMiddlewareHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestMiddlewareHandler_ErrorBody(t *testing.T) {
+	const inputRequestId = "test-request-id"
+
+	testCases := []struct {
+		name                  string
+		inputHandlerResp      *handlerResponse
+		inputRequestId        string
+		expectedStatusCode    int
+		expectedCode          string
+		expectedValidSortKeys []string
+	}{
+		{
+			name: "error without code - code derived from status",
+			inputHandlerResp: &handlerResponse{
+				Err:        errors.New("Stub Handler Response Error"),
+				StatusCode: http.StatusInternalServerError,
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedCode:       "internal_server_error",
+		},
+		{
+			name: "error with code - caller provided request id",
+			inputHandlerResp: &handlerResponse{
+				Err:        errors.New("Stub Handler Response Error"),
+				StatusCode: http.StatusBadRequest,
+				Code:       errCodeInvalidSortKey,
+			},
+			inputRequestId:     inputRequestId,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       errCodeInvalidSortKey,
+		},
+		{
+			name: "invalid sort key error lists valid keys",
+			inputHandlerResp: &handlerResponse{
+				Err:        &invalidSortKeyError{segment: "unsupported"},
+				StatusCode: http.StatusBadRequest,
+				Code:       errCodeInvalidSortKey,
+			},
+			expectedStatusCode:    http.StatusBadRequest,
+			expectedCode:          errCodeInvalidSortKey,
+			expectedValidSortKeys: validSortOptions,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			handler := middlewareHandler(func(w http.ResponseWriter, r *http.Request) *handlerResponse {
+				return tc.inputHandlerResp
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.inputRequestId != "" {
+				req.Header.Set(requestIdHeader, tc.inputRequestId)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			resp := rec.Result()
+			defer resp.Body.Close()
+			if resp.StatusCode != tc.expectedStatusCode {
+				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
+					tc.name, tc.expectedStatusCode, resp.StatusCode)
+			}
+			if contentType := resp.Header.Get("Content-Type"); contentType != contentTypeProblemJson {
+				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
+					tc.name, contentTypeProblemJson, contentType)
+			}
+
+			errResp := new(types.ErrorResponse)
+			if err := json.NewDecoder(resp.Body).Decode(errResp); err != nil {
+				t.Fatalf("Internal Testing error: %v", err)
+			}
+			if errResp.Status != tc.expectedStatusCode || errResp.Detail != tc.inputHandlerResp.Error() {
+				t.Fatalf("Test Failed: %v Unexpected Error Response: %+v", tc.name, errResp)
+			}
+			if errResp.Code != tc.expectedCode {
+				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
+					tc.name, tc.expectedCode, errResp.Code)
+			}
+			if errResp.RequestId == "" || errResp.RequestId != resp.Header.Get(requestIdHeader) {
+				t.Fatalf("Test Failed: %v Expected Request ID %q to match header %q",
+					tc.name, errResp.RequestId, resp.Header.Get(requestIdHeader))
+			}
+			if tc.inputRequestId != "" && errResp.RequestId != tc.inputRequestId {
+				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
+					tc.name, tc.inputRequestId, errResp.RequestId)
+			}
+			if !reflect.DeepEqual(errResp.ValidSortKeys, tc.expectedValidSortKeys) {
+				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
+					tc.name, tc.expectedValidSortKeys, errResp.ValidSortKeys)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+const (
+	envVarNormalizeStripWww       = "URL_NORMALIZE_STRIP_WWW"
+	envVarNormalizeTrackingParams = "URL_NORMALIZE_TRACKING_PARAMS"
+
+	canonicalScheme = "https"
+	schemeSeparator = "://"
+	wwwPrefix       = "www."
+)
+
+var defaultTrackingParams = []string{"utm_"}
+
+// NormalizeConfig controls how urls are canonicalized. TrackingParams holds query parameter
+// prefixes, such as "utm_", that are dropped. A nil TrackingParams falls back to the default.
+type NormalizeConfig struct {
+	StripWww       bool
+	TrackingParams []string
+}
+
+func (c NormalizeConfig) getTrackingParams() []string {
+	if c.TrackingParams == nil {
+		return defaultTrackingParams
+	}
+	return c.TrackingParams
+}
+
+// normalizeUrlStats sets NormalizedUrl on every record, keeping Url untouched
+func (c NormalizeConfig) normalizeUrlStats(urlStats types.UrlStatSlice) {
+	for _, urlStat := range urlStats {
+		if urlStat != nil {
+			urlStat.NormalizedUrl = c.normalizeUrl(urlStat.Url)
+		}
+	}
+}
+
+// normalizeUrl canonicalizes the scheme to https, lowercases the host, drops default ports,
+// trailing slashes and tracking parameters and sorts the query parameters.
+// Values that can not be parsed are only trimmed, with their scheme and host lowercased.
+func (c NormalizeConfig) normalizeUrl(rawUrl string) string {
+	rawUrl = strings.TrimSpace(rawUrl)
+	if rawUrl == "" {
+		return ""
+	}
+	if !hasScheme(rawUrl) {
+		rawUrl = canonicalScheme + schemeSeparator + rawUrl
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Host == "" {
+		return lowercaseSchemeAndHost(rawUrl)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme == "http" {
+		u.Scheme = canonicalScheme
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if c.StripWww {
+		host = strings.TrimPrefix(host, wwwPrefix)
+	}
+	// Hostname strips the brackets of IPv6 literals, which JoinHostPort adds back
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	u.Host = host
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+
+	query := u.Query()
+	for key := range query {
+		if c.isTrackingParam(key) {
+			query.Del(key)
+		}
+	}
+	// Encode sorts the parameters by key
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+	return u.String()
+}
+
+// hasScheme only looks for the scheme separator before the path, query and fragment,
+// which may hold urls of their own, e.g. "www.example.com/?next=https://www.example.org"
+func hasScheme(rawUrl string) bool {
+	i := strings.Index(rawUrl, schemeSeparator)
+	return i > 0 && !strings.ContainsAny(rawUrl[:i], "/?#")
+}
+
+// lowercaseSchemeAndHost leaves the path, query and fragment as they are, as they are case sensitive
+func lowercaseSchemeAndHost(rawUrl string) string {
+	scheme, rest, _ := strings.Cut(rawUrl, schemeSeparator)
+	end := strings.IndexAny(rest, "/?#")
+	if end < 0 {
+		end = len(rest)
+	}
+	return strings.ToLower(scheme) + schemeSeparator + strings.ToLower(rest[:end]) + rest[end:]
+}
+
+func (c NormalizeConfig) isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	for _, prefix := range c.getTrackingParams() {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeConfigFromEnv reads URL_NORMALIZE_STRIP_WWW and URL_NORMALIZE_TRACKING_PARAMS,
+// a comma separated list of query parameter prefixes replacing the default one.
+func NormalizeConfigFromEnv(getenv func(string) string) (NormalizeConfig, error) {
+	var config NormalizeConfig
+	if value := getenv(envVarNormalizeStripWww); value != "" {
+		var err error
+		if config.StripWww, err = strconv.ParseBool(value); err != nil {
+			return config, fmt.Errorf("invalid %s: %w", envVarNormalizeStripWww, err)
+		}
+	}
+	if value := getenv(envVarNormalizeTrackingParams); value != "" {
+		config.TrackingParams = []string{}
+		for _, prefix := range strings.Split(value, ",") {
+			if prefix = strings.ToLower(strings.TrimSpace(prefix)); prefix != "" {
+				config.TrackingParams = append(config.TrackingParams, prefix)
+			}
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+func TestNormalizeUrl(t *testing.T) {
+	testCases := []struct {
+		name        string
+		inputConfig NormalizeConfig
+		input       string
+		expected    string
+	}{
+		{
+			name:     "scheme-less url",
+			input:    "www.yahoo.com/abc6",
+			expected: "https://www.yahoo.com/abc6",
+		},
+		{
+			name:     "http scheme and uppercase host",
+			input:    "HTTP://WWW.Example.com/Abc1",
+			expected: "https://www.example.com/Abc1",
+		},
+		{
+			name:        "strip www",
+			inputConfig: NormalizeConfig{StripWww: true},
+			input:       "https://www.example.com/abc1",
+			expected:    "https://example.com/abc1",
+		},
+		{
+			name:     "trailing slashes",
+			input:    "www.example.com/abc1//",
+			expected: "https://www.example.com/abc1",
+		},
+		{
+			name:     "default port",
+			input:    "https://www.example.com:443/abc1",
+			expected: "https://www.example.com/abc1",
+		},
+		{
+			name:     "custom port",
+			input:    "www.example.com:8080/abc1",
+			expected: "https://www.example.com:8080/abc1",
+		},
+		{
+			name:     "ipv6 host",
+			input:    "http://[::1]/abc1",
+			expected: "https://[::1]/abc1",
+		},
+		{
+			name:     "ipv6 host and default port",
+			input:    "https://[::1]:443/abc1",
+			expected: "https://[::1]/abc1",
+		},
+		{
+			name:     "ipv6 host and custom port",
+			input:    "http://[::1]:8080/abc1",
+			expected: "https://[::1]:8080/abc1",
+		},
+		{
+			name:     "uppercase ipv6 host",
+			input:    "http://[2001:DB8::1]/abc1",
+			expected: "https://[2001:db8::1]/abc1",
+		},
+		{
+			name:     "scheme-less ipv6 host",
+			input:    "[2001:db8::1]/abc1",
+			expected: "https://[2001:db8::1]/abc1",
+		},
+		{
+			name:     "sorted query, tracking parameters dropped",
+			input:    "www.example.com/abc1?b=2&utm_source=news&a=1&UTM_Medium=email",
+			expected: "https://www.example.com/abc1?a=1&b=2",
+		},
+		{
+			name:        "custom tracking parameters",
+			inputConfig: NormalizeConfig{TrackingParams: []string{"fbclid"}},
+			input:       "www.example.com/abc1?fbclid=1&utm_source=news",
+			expected:    "https://www.example.com/abc1?utm_source=news",
+		},
+		{
+			name:     "only tracking parameters",
+			input:    "www.example.com/abc1/?utm_source=news",
+			expected: "https://www.example.com/abc1",
+		},
+		{
+			name:     "scheme-less url with a url in the query",
+			input:    "www.example.com/?next=https://www.example.org",
+			expected: "https://www.example.com?next=https%3A%2F%2Fwww.example.org",
+		},
+		{
+			name:     "scheme-less url with a url in the query, sibling",
+			input:    "www.example.com/?x=1",
+			expected: "https://www.example.com?x=1",
+		},
+		{
+			name:     "scheme-less url with a url in the path",
+			input:    "WWW.Example.com/redirect/https://www.example.org",
+			expected: "https://www.example.com/redirect/https://www.example.org",
+		},
+		{
+			name:     "scheme-less url with a url in the fragment",
+			input:    "www.example.com/abc1#https://www.example.org",
+			expected: "https://www.example.com/abc1#https://www.example.org",
+		},
+		{
+			name:     "invalid url: path and query kept as they are",
+			input:    "WWW.Exa mple.com/Abc1?Q=A",
+			expected: "https://www.exa mple.com/Abc1?Q=A",
+		},
+		{
+			name:     "invalid url with scheme: path and query kept as they are",
+			input:    "HTTP://WWW.Exa mple.com/Abc1?next=https://B.com",
+			expected: "http://www.exa mple.com/Abc1?next=https://B.com",
+		},
+		{
+			name:     "surrounding spaces",
+			input:    " www.example.com/abc1 ",
+			expected: "https://www.example.com/abc1",
+		},
+		{
+			name:     "empty",
+			input:    "",
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result := tc.inputConfig.normalizeUrl(tc.input)
+			if result != tc.expected {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestAggregate_NormalizedDuplicates(t *testing.T) {
+	urlStatService := urlStatDataService{
+		normalize: NormalizeConfig{StripWww: true},
+		merge:     MergeConfig{Views: MergeSum},
+	}
+	batches := []sourceBatch{
+		{source: "a.json", data: types.UrlStatSlice{{Url: "www.example.com/abc1/", Views: 1000}}},
+		{source: "b.json", data: types.UrlStatSlice{{Url: "https://example.com/abc1?utm_source=news", Views: 2000}}},
+	}
+	expected := types.UrlStatSlice{
+		{
+			Url:           "www.example.com/abc1/",
+			NormalizedUrl: "https://example.com/abc1",
+			Views:         3000,
+			Sources:       []string{"a.json", "b.json"},
+		},
+	}
+
+	result := urlStatService.aggregate(batches)
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", expected.String(), result.String())
+	}
+}
+
+func TestNormalizeConfigFromEnv(t *testing.T) {
+	testCases := []struct {
+		name        string
+		inputEnv    map[string]string
+		expected    NormalizeConfig
+		expectedErr bool
+	}{
+		{
+			name: "no env: defaults",
+		},
+		{
+			name: "strip www and custom tracking parameters",
+			inputEnv: map[string]string{
+				envVarNormalizeStripWww:       "true",
+				envVarNormalizeTrackingParams: "utm_, FBCLID,",
+			},
+			expected: NormalizeConfig{StripWww: true, TrackingParams: []string{"utm_", "fbclid"}},
+		},
+		{
+			name: "invalid strip www",
+			inputEnv: map[string]string{
+				envVarNormalizeStripWww: "unsupported",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result, resultErr := NormalizeConfigFromEnv(func(key string) string {
+				return tc.inputEnv[key]
+			})
+			assertErr := resultErr != nil
+			if assertErr != tc.expectedErr {
+				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
+					tc.name, tc.expectedErr, resultErr)
+			}
+			if !tc.expectedErr && !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %+v Actual Result: %+v",
+					tc.name, tc.expected, result)
+			}
+		})
+	}
+}
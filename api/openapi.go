@@ -0,0 +1,22 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+const openApiPath = "/openapi.json"
+
+// openApiSpec describes every route registered by routes()
+//
+//go:embed openapi.json
+var openApiSpec []byte
+
+func (s *apiServer) handleOpenApi(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", contentTypeJson)
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	_, _ = w.Write(openApiSpec)
+}
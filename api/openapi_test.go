@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+type openApiDocument struct {
+	OpenApi    string                                `json:"openapi"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Parameters map[string]struct {
+			Name string `json:"name"`
+			In   string `json:"in"`
+		} `json:"parameters"`
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func getOpenApiDocument(t *testing.T) *openApiDocument {
+	t.Helper()
+	doc := new(openApiDocument)
+	if err := json.Unmarshal(openApiSpec, doc); err != nil {
+		t.Fatalf("Test Failed. Invalid OpenAPI document: %v", err)
+	}
+	return doc
+}
+
+func TestOpenApiSpec_Routes(t *testing.T) {
+	doc := getOpenApiDocument(t)
+	apiServer := NewApiServer(&stubService{data: newStubUrlStatData()})
+
+	routed := map[string]bool{}
+	for _, rt := range apiServer.routes() {
+		routed[rt.label()] = true
+		if _, ok := doc.Paths[rt.label()][strings.ToLower(rt.method)]; !ok {
+			t.Fatalf("Test Failed. Route %v is not described in the OpenAPI document", rt.pattern())
+		}
+	}
+	for path := range doc.Paths {
+		if !routed[path] {
+			t.Fatalf("Test Failed. Path %v of the OpenAPI document is not routed", path)
+		}
+	}
+}
+
+func TestOpenApiSpec_Parameters(t *testing.T) {
+	doc := getOpenApiDocument(t)
+
+	var result []string
+	for _, parameter := range doc.Components.Parameters {
+		result = append(result, parameter.Name)
+	}
+	expected := []string{
+		sortKeyPathValue, strictFilterOption, limitFilterOption, offsetFilterOption, cursorFilterOption,
+		strictSourcesOption, minViewsFilterOption, maxViewsFilterOption, minRelevanceFilterOption,
+		maxRelevanceFilterOption, hostFilterOption, urlPrefixFilterOption, urlMatchFilterOption,
+		expressionFilterOption, formatOption,
+	}
+	sort.Strings(result)
+	sort.Strings(expected)
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", expected, result)
+	}
+}
+
+func TestOpenApiSpec_Schemas(t *testing.T) {
+	doc := getOpenApiDocument(t)
+
+	testCases := []struct {
+		name  string
+		input any
+	}{
+		{name: "ResponseUrlStats", input: types.ResponseUrlStats{}},
+		{name: "UrlStat", input: types.UrlStat{}},
+		{name: "Snapshot", input: types.Snapshot{}},
+		{name: "SourceStatus", input: types.SourceStatus{}},
+		{name: "Violation", input: types.Violation{}},
+		{name: "HealthStatus", input: types.HealthStatus{}},
+		{name: "ErrorResponse", input: types.ErrorResponse{}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			schema, ok := doc.Components.Schemas[tc.name]
+			if !ok {
+				t.Fatalf("Test Failed: %v. Schema is not described in the OpenAPI document", tc.name)
+			}
+
+			var expected, result []string
+			typ := reflect.TypeOf(tc.input)
+			for i := 0; i < typ.NumField(); i++ {
+				name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+				if name != "" && name != "-" {
+					expected = append(expected, name)
+				}
+			}
+			for property := range schema.Properties {
+				result = append(result, property)
+			}
+			sort.Strings(expected)
+			sort.Strings(result)
+			if !reflect.DeepEqual(result, expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v", tc.name, expected, result)
+			}
+		})
+	}
+}
+
+func TestHandleOpenApi(t *testing.T) {
+	apiServer := NewApiServer(&stubService{data: newStubUrlStatData()})
+	rec := httptest.NewRecorder()
+	apiServer.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, openApiPath, nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", http.StatusOK, rec.Code)
+	}
+	if contentType := rec.Header().Get("Content-Type"); contentType != contentTypeJson {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", contentTypeJson, contentType)
+	}
+	doc := new(openApiDocument)
+	if err := json.Unmarshal(rec.Body.Bytes(), doc); err != nil {
+		t.Fatalf("Test Failed. Invalid OpenAPI document: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenApi, "3.") {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", "3.x", doc.OpenApi)
+	}
+}
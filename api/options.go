@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"time"
+)
+
+type ServiceOption func(*urlStatDataService)
+
+// WithFetchTimeout bounds the time spent collecting data from all HTTP Data Source Endpoints
+func WithFetchTimeout(timeout time.Duration) ServiceOption {
+	return func(uS *urlStatDataService) {
+		uS.fetchTimeout = timeout
+	}
+}
+
+// WithAttemptTimeout bounds the time spent on a single HTTP GET attempt, including reading the body
+func WithAttemptTimeout(timeout time.Duration) ServiceOption {
+	return func(uS *urlStatDataService) {
+		uS.attemptTimeout = timeout
+	}
+}
+
+func WithRetryPolicy(policy RetryPolicy) ServiceOption {
+	return func(uS *urlStatDataService) {
+		uS.retryPolicy = &policy
+	}
+}
+
+// WithMaxConcurrency limits the number of HTTP GET requests in flight, overall and against a single host
+func WithMaxConcurrency(maxConcurrency, maxConcurrencyPerHost int) ServiceOption {
+	return func(uS *urlStatDataService) {
+		uS.maxConcurrency = maxConcurrency
+		uS.maxConcurrencyPerHost = maxConcurrencyPerHost
+	}
+}
+
+func WithHttpClient(client *http.Client) ServiceOption {
+	return func(uS *urlStatDataService) {
+		uS.httpClient = client
+	}
+}
+
+func WithValidation(config ValidationConfig) ServiceOption {
+	return func(uS *urlStatDataService) {
+		uS.validation = config
+	}
+}
+
+func WithMergeConfig(config MergeConfig) ServiceOption {
+	return func(uS *urlStatDataService) {
+		uS.merge = config
+	}
+}
+
+func WithNormalizeConfig(config NormalizeConfig) ServiceOption {
+	return func(uS *urlStatDataService) {
+		uS.normalize = config
+	}
+}
+
+type ServerOption func(*apiServer)
+
+// WithServerConfig sets the HTTP server timeouts and the shutdown drain period
+func WithServerConfig(config ServerConfig) ServerOption {
+	return func(s *apiServer) {
+		s.config = config
+	}
+}
+
+// WithMaxSnapshotAge is the age past which the served snapshot makes the server not ready
+func WithMaxSnapshotAge(maxAge time.Duration) ServerOption {
+	return func(s *apiServer) {
+		s.maxSnapshotAge = maxAge
+	}
+}
+
+// WithCompression sets the minimum size and the level of compressed responses
+func WithCompression(config CompressionConfig) ServerOption {
+	return func(s *apiServer) {
+		s.compression = config
+	}
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"math"
+	"net/url"
+	"strconv"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+// pageCursor is handed out to clients as an opaque token. It pins the snapshot Version the
+// pages are served from, so walking the pages shows no duplicates or gaps while the data refreshes.
+type pageCursor struct {
+	Version     uint64 `json:"v"`
+	Offset      int    `json:"o"`
+	Limit       int    `json:"l"`
+	Fingerprint string `json:"f"`
+}
+
+func (c pageCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodePageCursor(token string) (pageCursor, error) {
+	var c pageCursor
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, errors.New("malformed cursor")
+	}
+	// The cursors handed out never end past the largest int
+	if err := json.Unmarshal(b, &c); err != nil || c.Offset < 0 || c.Limit <= 0 || c.Limit > math.MaxInt-c.Offset {
+		return c, errors.New("malformed cursor")
+	}
+	return c, nil
+}
+
+// pageRequest holds the requested page. version is 0 unless a cursor pinned a snapshot
+type pageRequest struct {
+	offset      int
+	limit       int
+	version     uint64
+	fingerprint string
+}
+
+// parsePageRequest reads offset and limit, or takes both from the cursor when one is given
+func parsePageRequest(sortBy string, query url.Values) (pageRequest, error) {
+	page := pageRequest{
+		offset:      getOffsetValue(query),
+		limit:       getLimitValue(query),
+		fingerprint: getQueryFingerprint(sortBy, query),
+	}
+	token := query.Get(cursorFilterOption)
+	if token == "" {
+		return page, nil
+	}
+
+	cursor, err := decodePageCursor(token)
+	if err != nil {
+		return page, &invalidRequestError{code: errCodeInvalidCursor, err: err}
+	}
+	if cursor.Fingerprint != page.fingerprint {
+		return page, &invalidRequestError{
+			code: errCodeInvalidCursor,
+			err:  errors.New("cursor does not match the sort keys and filters of the request")}
+	}
+	page.offset = cursor.Offset
+	page.limit = cursor.Limit
+	page.version = cursor.Version
+	return page, nil
+}
+
+// getQueryFingerprint identifies the result set a cursor belongs to: the sort keys and every
+// query parameter except the ones selecting the page or how it is served
+func getQueryFingerprint(sortBy string, query url.Values) string {
+	resultSetQuery := url.Values{}
+	for key, values := range query {
+		switch key {
+		case cursorFilterOption, offsetFilterOption, limitFilterOption, strictSourcesOption, formatOption:
+		default:
+			resultSetQuery[key] = values
+		}
+	}
+	h := fnv.New64a()
+	h.Write([]byte(sortBy + "?" + resultSetQuery.Encode()))
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+// getUrlStatsDataForPage serves the snapshot pinned by the cursor, if any and if the service keeps snapshots
+func (s *apiServer) getUrlStatsDataForPage(ctx context.Context, page pageRequest) (*types.UrlStatData, error) {
+	if s.snapshots != nil && page.version != 0 {
+		return s.snapshots.getUrlStatsDataVersion(ctx, page.version)
+	}
+	return s.svc.getUrlStatsData(ctx)
+}
+
+// newPagedResponse pages through the sorted records and sets the cursors of the adjacent pages.
+// total is the number of records in the result set, as sorted may end with the requested page.
+func newPagedResponse(sorted *types.UrlStatSlice, total int, page pageRequest, snapshot *types.Snapshot) (*types.ResponseUrlStats, error) {
+	pageUrlStats, err := paginate(sorted, page.offset, page.limit)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &types.ResponseUrlStats{
+		SortedUrlStats: pageUrlStats,
+		Count:          len(*pageUrlStats),
+		Total:          total,
+		Snapshot:       snapshot,
+	}
+	resp.HasMore = page.offset+resp.Count < resp.Total
+	if page.limit <= 0 {
+		return resp, nil
+	}
+
+	cursor := pageCursor{Limit: page.limit, Fingerprint: page.fingerprint}
+	if snapshot != nil {
+		cursor.Version = snapshot.Version
+	}
+	if resp.HasMore {
+		cursor.Offset = page.offset + resp.Count
+		resp.NextCursor = cursor.encode()
+	}
+	if page.offset > 0 {
+		// An offset past the end leads back to the last page
+		cursor.Offset = page.offset - page.limit
+		if page.offset > resp.Total {
+			cursor.Offset = resp.Total - page.limit
+		}
+		if cursor.Offset < 0 {
+			cursor.Offset = 0
+		}
+		resp.PrevCursor = cursor.encode()
+	}
+	return resp, nil
+}
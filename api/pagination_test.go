@@ -0,0 +1,352 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func getSortKeyPage(t *testing.T, apiServer *apiServer, sortBy string, query url.Values) *handlerResponse {
+	t.Helper()
+	req := newSortKeyRequest(fmt.Sprintf("/%s/%s?%s", sortkeyPath, sortBy, query.Encode()))
+	return apiServer.handleSortKey(httptest.NewRecorder(), req)
+}
+
+func TestHandleSortKey_offsetPagination(t *testing.T) {
+	testCases := []struct {
+		name            string
+		inputQuery      url.Values
+		expectedUrls    []string
+		expectedTotal   int
+		expectedHasMore bool
+		expectedNext    bool
+		expectedPrev    bool
+	}{
+		{
+			name:            "first page",
+			inputQuery:      url.Values{limitFilterOption: {"2"}},
+			expectedUrls:    []string{"www.example.com/abc1", "www.example.com/abc2"},
+			expectedTotal:   5,
+			expectedHasMore: true,
+			expectedNext:    true,
+		},
+		{
+			name:            "middle page",
+			inputQuery:      url.Values{offsetFilterOption: {"2"}, limitFilterOption: {"2"}},
+			expectedUrls:    []string{"www.example.com/abc3", "www.example.com/abc4"},
+			expectedTotal:   5,
+			expectedHasMore: true,
+			expectedNext:    true,
+			expectedPrev:    true,
+		},
+		{
+			name:          "last page",
+			inputQuery:    url.Values{offsetFilterOption: {"4"}, limitFilterOption: {"2"}},
+			expectedUrls:  []string{"www.example.com/abc5"},
+			expectedTotal: 5,
+			expectedPrev:  true,
+		},
+		{
+			name:          "offset without limit",
+			inputQuery:    url.Values{offsetFilterOption: {"3"}},
+			expectedUrls:  []string{"www.example.com/abc4", "www.example.com/abc5"},
+			expectedTotal: 5,
+		},
+		{
+			name:          "offset beyond total",
+			inputQuery:    url.Values{offsetFilterOption: {"10"}, limitFilterOption: {"2"}},
+			expectedUrls:  []string{},
+			expectedTotal: 5,
+			expectedPrev:  true,
+		},
+		{
+			name:            "total counts filtered records",
+			inputQuery:      url.Values{minViewsFilterOption: {"4000"}, limitFilterOption: {"1"}},
+			expectedUrls:    []string{"www.example.com/abc4"},
+			expectedTotal:   2,
+			expectedHasMore: true,
+			expectedNext:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			apiServer := NewApiServer(&stubService{data: newUrlStatTestData(5)})
+			handlerResp := getSortKeyPage(t, apiServer, viewsOption, tc.inputQuery)
+			if handlerResp.StatusCode != http.StatusOK {
+				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
+					tc.name, http.StatusOK, handlerResp.StatusCode)
+			}
+			resp := handlerResp.resp
+			if result := filteredUrls(*resp.SortedUrlStats); !reflect.DeepEqual(result, tc.expectedUrls) {
+				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v",
+					tc.name, tc.expectedUrls, result)
+			}
+			if resp.Count != len(tc.expectedUrls) || resp.Total != tc.expectedTotal || resp.HasMore != tc.expectedHasMore {
+				t.Fatalf("Test Failed: %v Expected Result: count %v total %v hasMore %v Actual Result: count %v total %v hasMore %v",
+					tc.name, len(tc.expectedUrls), tc.expectedTotal, tc.expectedHasMore, resp.Count, resp.Total, resp.HasMore)
+			}
+			if (resp.NextCursor != "") != tc.expectedNext || (resp.PrevCursor != "") != tc.expectedPrev {
+				t.Fatalf("Test Failed: %v Expected Result: next %v prev %v Actual Result: next %q prev %q",
+					tc.name, tc.expectedNext, tc.expectedPrev, resp.NextCursor, resp.PrevCursor)
+			}
+		})
+	}
+}
+
+func TestHandleSortKey_cursorPagination(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stub := &stubService{data: newUrlStatTestData(5)}
+	c := NewCachingService(ctx, stub, time.Hour).(*cachingService)
+	apiServer := NewApiServer(c)
+
+	var urls []string
+	query := url.Values{limitFilterOption: {"2"}}
+	var prevCursor string
+	for page := 0; ; page++ {
+		handlerResp := getSortKeyPage(t, apiServer, viewsOption, query)
+		if handlerResp.StatusCode != http.StatusOK {
+			t.Fatalf("Test Failed. Page %v Expected Result: %v Actual Result: %v Error: %v",
+				page, http.StatusOK, handlerResp.StatusCode, handlerResp.Err)
+		}
+		urls = append(urls, filteredUrls(*handlerResp.resp.SortedUrlStats)...)
+		if page == 1 {
+			prevCursor = handlerResp.resp.PrevCursor
+		}
+		if !handlerResp.resp.HasMore {
+			break
+		}
+
+		// The data changes between pages, but the cursor keeps serving the snapshot it was created from
+		stub.mu.Lock()
+		stub.data = newUrlStatTestData(page + 1)
+		stub.mu.Unlock()
+		if err := c.refresh(ctx); err != nil {
+			t.Fatalf("Internal Testing error: %v", err)
+		}
+		query = url.Values{cursorFilterOption: {handlerResp.resp.NextCursor}}
+	}
+
+	expected := filteredUrls(newUrlStatTestData(5).Data)
+	if !reflect.DeepEqual(urls, expected) {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", expected, urls)
+	}
+
+	handlerResp := getSortKeyPage(t, apiServer, viewsOption, url.Values{cursorFilterOption: {prevCursor}})
+	if result := filteredUrls(*handlerResp.resp.SortedUrlStats); !reflect.DeepEqual(result, expected[:2]) {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", expected[:2], result)
+	}
+	if !handlerResp.resp.Snapshot.Stale {
+		t.Fatalf("Test Failed. Expected a previous snapshot to be flagged as stale")
+	}
+}
+
+// With pages ending within the first 1/topKThreshold of the records, only the page is sorted.
+// The total and the cursors still cover every record.
+func TestHandleSortKey_topKPagination(t *testing.T) {
+	const inputRecords = 20
+
+	testCases := []struct {
+		name          string
+		inputSortBy   string
+		inputQuery    url.Values
+		expectedTotal int
+	}{
+		{
+			name:          "single key",
+			inputSortBy:   viewsOption,
+			inputQuery:    url.Values{limitFilterOption: {"2"}},
+			expectedTotal: inputRecords,
+		},
+		{
+			name:          "multiple keys",
+			inputSortBy:   "views:desc,url",
+			inputQuery:    url.Values{limitFilterOption: {"2"}},
+			expectedTotal: inputRecords,
+		},
+		{
+			name:          "filtered",
+			inputSortBy:   viewsOption,
+			inputQuery:    url.Values{minViewsFilterOption: {"3000"}, limitFilterOption: {"1"}},
+			expectedTotal: inputRecords - 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			apiServer := NewApiServer(&stubService{data: newUrlStatTestData(inputRecords)})
+
+			first := getSortKeyPage(t, apiServer, tc.inputSortBy, tc.inputQuery)
+			if first.StatusCode != http.StatusOK {
+				t.Fatalf("Internal Testing error: %v", first.Err)
+			}
+			if first.resp.Total != tc.expectedTotal || !first.resp.HasMore || first.resp.NextCursor == "" {
+				t.Fatalf("Test Failed: %v Expected Result: total %v hasMore %v Actual Result: total %v hasMore %v next %q",
+					tc.name, tc.expectedTotal, true, first.resp.Total, first.resp.HasMore, first.resp.NextCursor)
+			}
+
+			// The whole result set, sorted in a single page
+			query := url.Values{}
+			for key, values := range tc.inputQuery {
+				query[key] = values
+			}
+			query.Del(limitFilterOption)
+			all := getSortKeyPage(t, apiServer, tc.inputSortBy, query)
+			expected := filteredUrls(*all.resp.SortedUrlStats)
+
+			urls := filteredUrls(*first.resp.SortedUrlStats)
+			resp := first.resp
+			for pages := 1; resp.HasMore; pages++ {
+				if pages > tc.expectedTotal {
+					t.Fatalf("Test Failed: %v. The cursors never reach the last record", tc.name)
+				}
+				query.Set(cursorFilterOption, resp.NextCursor)
+				handlerResp := getSortKeyPage(t, apiServer, tc.inputSortBy, query)
+				if handlerResp.StatusCode != http.StatusOK {
+					t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v Error: %v",
+						tc.name, http.StatusOK, handlerResp.StatusCode, handlerResp.Err)
+				}
+				resp = handlerResp.resp
+				if resp.Total != tc.expectedTotal {
+					t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v", tc.name, tc.expectedTotal, resp.Total)
+				}
+				urls = append(urls, filteredUrls(*resp.SortedUrlStats)...)
+			}
+			if !reflect.DeepEqual(urls, expected) {
+				t.Fatalf("Test Failed: %v Expected Result: %v Actual Result: %v", tc.name, expected, urls)
+			}
+		})
+	}
+}
+
+func TestHandleSortKey_largestLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	testCases := []struct {
+		name        string
+		inputSvc    service
+		inputSortBy string
+	}{
+		{
+			name:        "indexed sort key",
+			inputSvc:    NewCachingService(ctx, &stubService{data: newUrlStatTestData(5)}, time.Hour),
+			inputSortBy: viewsOption,
+		},
+		{
+			name:        "multiple sort keys",
+			inputSvc:    &stubService{data: newUrlStatTestData(5)},
+			inputSortBy: "views:desc,url",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			apiServer := NewApiServer(tc.inputSvc)
+			query := url.Values{offsetFilterOption: {"1"}, limitFilterOption: {strconv.Itoa(math.MaxInt)}}
+			handlerResp := getSortKeyPage(t, apiServer, tc.inputSortBy, query)
+			if handlerResp.StatusCode != http.StatusOK {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v Error: %v",
+					tc.name, http.StatusOK, handlerResp.StatusCode, handlerResp.Err)
+			}
+			if resp := handlerResp.resp; resp.Count != 4 || resp.HasMore || resp.NextCursor != "" {
+				t.Fatalf("Test Failed: %v. Expected Result: count %v hasMore %v Actual Result: count %v hasMore %v next %q",
+					tc.name, 4, false, resp.Count, resp.HasMore, resp.NextCursor)
+			}
+		})
+	}
+}
+
+func TestHandleSortKey_cursorErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stub := &stubService{data: newUrlStatTestData(5)}
+	c := NewCachingService(ctx, stub, time.Hour).(*cachingService)
+	apiServer := NewApiServer(c)
+
+	firstPage := getSortKeyPage(t, apiServer, viewsOption, url.Values{limitFilterOption: {"2"}})
+	if firstPage.StatusCode != http.StatusOK {
+		t.Fatalf("Internal Testing error: %v", firstPage.Err)
+	}
+	nextCursor := firstPage.resp.NextCursor
+
+	testCases := []struct {
+		name               string
+		inputSortBy        string
+		inputQuery         url.Values
+		inputRefreshes     int
+		expectedStatusCode int
+		expectedCode       string
+	}{
+		{
+			name:               "malformed cursor",
+			inputSortBy:        viewsOption,
+			inputQuery:         url.Values{cursorFilterOption: {"not-a-cursor"}},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       errCodeInvalidCursor,
+		},
+		{
+			name:               "cursor used with other sort keys",
+			inputSortBy:        relevancescoreOption,
+			inputQuery:         url.Values{cursorFilterOption: {nextCursor}},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       errCodeInvalidCursor,
+		},
+		{
+			name:               "cursor used with other filters",
+			inputSortBy:        viewsOption,
+			inputQuery:         url.Values{cursorFilterOption: {nextCursor}, minViewsFilterOption: {"1"}},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       errCodeInvalidCursor,
+		},
+		{
+			name:        "cursor ending past the largest int",
+			inputSortBy: viewsOption,
+			inputQuery: url.Values{cursorFilterOption: {pageCursor{
+				Offset:      1,
+				Limit:       math.MaxInt,
+				Fingerprint: getQueryFingerprint(viewsOption, url.Values{}),
+			}.encode()}},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       errCodeInvalidCursor,
+		},
+		{
+			name:               "snapshot no longer kept",
+			inputSortBy:        viewsOption,
+			inputQuery:         url.Values{cursorFilterOption: {nextCursor}},
+			inputRefreshes:     snapshotHistory,
+			expectedStatusCode: http.StatusGone,
+			expectedCode:       errCodeCursorExpired,
+		},
+	}
+
+	// Not parallel: the last test case refreshes the shared cache
+	for _, tc := range testCases {
+		for i := 0; i < tc.inputRefreshes; i++ {
+			if err := c.refresh(ctx); err != nil {
+				t.Fatalf("Internal Testing error: %v", err)
+			}
+		}
+		handlerResp := getSortKeyPage(t, apiServer, tc.inputSortBy, tc.inputQuery)
+		if handlerResp.StatusCode != tc.expectedStatusCode || handlerResp.Code != tc.expectedCode {
+			t.Fatalf("Test Failed: %v Expected Result: %v %v Actual Result: %v %v",
+				tc.name, tc.expectedStatusCode, tc.expectedCode, handlerResp.StatusCode, handlerResp.Code)
+		}
+	}
+}
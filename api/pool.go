@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxConcurrency        = 32
+	defaultMaxConcurrencyPerHost = 4
+)
+
+// defaultHttpClient is shared by all services created without WithHttpClient,
+// so that connections to the HTTP Data Source Endpoints are pooled across fetch cycles
+var defaultHttpClient = NewHttpClient(defaultMaxConcurrency, defaultMaxConcurrencyPerHost)
+
+// NewHttpClient returns a client whose connection pool is sized for the given concurrency limits.
+// Timeouts are not set on the client, since they are driven by the request context.
+func NewHttpClient(maxConcurrency, maxConcurrencyPerHost int) *http.Client {
+	maxConcurrency = intOrDefault(maxConcurrency, defaultMaxConcurrency)
+	maxConcurrencyPerHost = intOrDefault(maxConcurrencyPerHost, defaultMaxConcurrencyPerHost)
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   5 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          maxConcurrency,
+			MaxIdleConnsPerHost:   maxConcurrencyPerHost,
+			MaxConnsPerHost:       maxConcurrencyPerHost,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+}
+
+func (uS *urlStatDataService) getHttpClient() *http.Client {
+	if uS.httpClient == nil {
+		return defaultHttpClient
+	}
+	return uS.httpClient
+}
+
+// fetchAll runs a bounded pool of workers. At most maxConcurrency requests are in flight overall
+// and at most maxConcurrencyPerHost against the same host. Results are returned in the order of urlAddrs.
+func (uS *urlStatDataService) fetchAll(ctx context.Context, urlAddrs []string) []*upstreamResult {
+	workers := intOrDefault(uS.maxConcurrency, defaultMaxConcurrency)
+	if workers > len(urlAddrs) {
+		workers = len(urlAddrs)
+	}
+	scheduler := newHostScheduler(urlAddrs, intOrDefault(uS.maxConcurrencyPerHost, defaultMaxConcurrencyPerHost))
+
+	results := make([]*upstreamResult, len(urlAddrs))
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i, ok := scheduler.next()
+				if !ok {
+					return
+				}
+				urlAddr := urlAddrs[i]
+				if err := ctx.Err(); err != nil {
+					results[i] = newUpstreamResult(urlAddr, nil, 0, 0, &upstreamError{Url: urlAddr, Err: err})
+				} else {
+					results[i] = uS.getUrlStatsDataHttpWithStatus(ctx, urlAddr)
+				}
+				scheduler.done(i)
+			}
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+// hostScheduler queues the URLs per host and hands them out in order, skipping the hosts without
+// a free slot. A slow host then only holds its own slots, instead of workers waiting for them.
+type hostScheduler struct {
+	limit int
+	hosts []string
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	queues   map[string][]int
+	inFlight map[string]int
+	pending  int
+}
+
+func newHostScheduler(urlAddrs []string, limit int) *hostScheduler {
+	h := &hostScheduler{
+		limit:    limit,
+		hosts:    make([]string, len(urlAddrs)),
+		queues:   make(map[string][]int),
+		inFlight: make(map[string]int),
+		pending:  len(urlAddrs),
+	}
+	h.cond = sync.NewCond(&h.mu)
+	for i, urlAddr := range urlAddrs {
+		h.hosts[i] = getHost(urlAddr)
+		h.queues[h.hosts[i]] = append(h.queues[h.hosts[i]], i)
+	}
+	return h
+}
+
+// next blocks until a host with queued URLs has a free slot, and returns the index of its first
+// URL. When several hosts have one, the URL listed first wins. It returns false once every URL
+// was handed out.
+func (h *hostScheduler) next() (int, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for h.pending > 0 {
+		best := -1
+		for host, queue := range h.queues {
+			if h.inFlight[host] < h.limit && (best < 0 || queue[0] < best) {
+				best = queue[0]
+			}
+		}
+		if best < 0 {
+			h.cond.Wait()
+			continue
+		}
+		host := h.hosts[best]
+		h.inFlight[host]++
+		h.pending--
+		if h.queues[host] = h.queues[host][1:]; len(h.queues[host]) == 0 {
+			delete(h.queues, host)
+		}
+		return best, true
+	}
+	return 0, false
+}
+
+// done frees the slot taken by the URL at index i
+func (h *hostScheduler) done(i int) {
+	h.mu.Lock()
+	h.inFlight[h.hosts[i]]--
+	h.mu.Unlock()
+	h.cond.Broadcast()
+}
+
+func getHost(urlAddr string) string {
+	u, err := url.Parse(urlAddr)
+	if err != nil {
+		return urlAddr
+	}
+	return u.Host
+}
+
+func intOrDefault(i, defaultInt int) int {
+	if i <= 0 {
+		return defaultInt
+	}
+	return i
+}
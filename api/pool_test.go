@@ -0,0 +1,208 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+// inFlightCounter records the highest number of concurrent requests
+type inFlightCounter struct {
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (c *inFlightCounter) add(delta int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight += delta
+	if c.inFlight > c.maxInFlight {
+		c.maxInFlight = c.inFlight
+	}
+}
+
+func (c *inFlightCounter) getMaxInFlight() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.maxInFlight
+}
+
+func newInFlightHandler(counters ...*inFlightCounter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, c := range counters {
+			c.add(1)
+		}
+		time.Sleep(20 * time.Millisecond)
+		for _, c := range counters {
+			c.add(-1)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&types.UrlStatData{
+			Data: []*types.UrlStat{{Url: "www.example.com" + r.URL.Path, Views: 1, RelevanceScore: 0.1}},
+		})
+	}
+}
+
+func TestFetchAll_Concurrency(t *testing.T) {
+	testCases := []struct {
+		name                string
+		inputServers        int
+		inputUrlsPerServer  int
+		inputMaxConcurrency int
+		inputMaxPerHost     int
+		expectedMaxInFlight int
+	}{
+		{
+			name:                "per host limit",
+			inputServers:        1,
+			inputUrlsPerServer:  10,
+			inputMaxConcurrency: 10,
+			inputMaxPerHost:     2,
+			expectedMaxInFlight: 2,
+		},
+		{
+			name:                "global limit across hosts",
+			inputServers:        3,
+			inputUrlsPerServer:  4,
+			inputMaxConcurrency: 3,
+			inputMaxPerHost:     4,
+			expectedMaxInFlight: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			global := &inFlightCounter{}
+			var perHost []*inFlightCounter
+			var urlAddrs []string
+			for i := 0; i < tc.inputServers; i++ {
+				host := &inFlightCounter{}
+				s := httptest.NewServer(newInFlightHandler(global, host))
+				defer s.Close()
+				perHost = append(perHost, host)
+				for j := 0; j < tc.inputUrlsPerServer; j++ {
+					urlAddrs = append(urlAddrs, fmt.Sprintf("%s/%d.json", s.URL, j))
+				}
+			}
+
+			urlStatService := urlStatDataService{
+				dataSourceType:        urlDataSourceHttp,
+				maxConcurrency:        tc.inputMaxConcurrency,
+				maxConcurrencyPerHost: tc.inputMaxPerHost,
+			}
+			results := urlStatService.fetchAll(context.Background(), urlAddrs)
+
+			if len(results) != len(urlAddrs) {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, len(urlAddrs), len(results))
+			}
+			for _, r := range results {
+				if r.err != nil {
+					t.Fatalf("Test Failed: %v. Unexpected Error: %v", tc.name, r.err)
+				}
+			}
+			if result := global.getMaxInFlight(); result > tc.expectedMaxInFlight {
+				t.Fatalf("Test Failed: %v. Expected at most %v requests in flight. Actual Result: %v",
+					tc.name, tc.expectedMaxInFlight, result)
+			}
+			for _, host := range perHost {
+				if result := host.getMaxInFlight(); result > tc.inputMaxPerHost {
+					t.Fatalf("Test Failed: %v. Expected at most %v requests per host. Actual Result: %v",
+						tc.name, tc.inputMaxPerHost, result)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchAll_SlowHostListedFirst(t *testing.T) {
+	const (
+		inputSlowUrls   = 6
+		inputFastUrls   = 4
+		inputMaxPerHost = 2
+	)
+
+	// The slow host answers once every URL of the fast host was fetched
+	fastFetched := make(chan struct{}, inputFastUrls)
+	release := make(chan struct{})
+	releaseSlow := sync.OnceFunc(func() { close(release) })
+	slow := &inFlightCounter{}
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slow.add(1)
+		defer slow.add(-1)
+		<-release
+		newInFlightHandler()(w, r)
+	}))
+	defer slowServer.Close()
+	defer releaseSlow()
+	fastServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		newInFlightHandler()(w, r)
+		fastFetched <- struct{}{}
+	}))
+	defer fastServer.Close()
+
+	var urlAddrs []string
+	for i := 0; i < inputSlowUrls; i++ {
+		urlAddrs = append(urlAddrs, fmt.Sprintf("%s/%d.json", slowServer.URL, i))
+	}
+	for i := 0; i < inputFastUrls; i++ {
+		urlAddrs = append(urlAddrs, fmt.Sprintf("%s/%d.json", fastServer.URL, i))
+	}
+
+	urlStatService := urlStatDataService{
+		dataSourceType:        urlDataSourceHttp,
+		maxConcurrency:        4,
+		maxConcurrencyPerHost: inputMaxPerHost,
+	}
+	done := make(chan []*upstreamResult)
+	go func() {
+		done <- urlStatService.fetchAll(context.Background(), urlAddrs)
+	}()
+
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < inputFastUrls; i++ {
+		select {
+		case <-fastFetched:
+		case <-timeout:
+			t.Fatalf("Test Failed. Expected the fast host to be fetched while the slow host is busy. Fetched: %v of %v", i, inputFastUrls)
+		}
+	}
+	if result := slow.getMaxInFlight(); result != inputMaxPerHost {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", inputMaxPerHost, result)
+	}
+	releaseSlow()
+
+	for _, r := range <-done {
+		if r.err != nil {
+			t.Fatalf("Test Failed. Unexpected Error: %v", r.err)
+		}
+	}
+}
+
+func TestFetchAll_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	urlStatService := urlStatDataService{dataSourceType: urlDataSourceHttp}
+	urlAddrs := []string{"http://127.0.0.1:1/a.json", "http://127.0.0.1:1/b.json"}
+	results := urlStatService.fetchAll(ctx, urlAddrs)
+
+	if len(results) != len(urlAddrs) {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", len(urlAddrs), len(results))
+	}
+	for _, r := range results {
+		if r.err == nil || r.status.Success {
+			t.Fatalf("Test Failed. Expected an error for %v. Actual Result: %+v", r.status.Source, r.status)
+		}
+	}
+}
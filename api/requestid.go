@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	requestIdHeader = "X-Request-ID"
+	requestIdBytes  = 8
+
+	// maxRequestIdLength bounds the caller provided request IDs, which end up in every log line
+	maxRequestIdLength = 64
+)
+
+type requestIdContextKey struct{}
+
+// getOrCreateRequestId reuses the caller provided request ID, if any and valid, so that IDs can be
+// correlated across services. The ID is echoed back in the response headers.
+func getOrCreateRequestId(w http.ResponseWriter, r *http.Request) string {
+	requestId := r.Header.Get(requestIdHeader)
+	if !isValidRequestId(requestId) {
+		requestId = newRequestId()
+	}
+	w.Header().Set(requestIdHeader, requestId)
+	return requestId
+}
+
+// isValidRequestId accepts up to maxRequestIdLength letters, digits, '.', '_' and '-'
+func isValidRequestId(requestId string) bool {
+	if requestId == "" || len(requestId) > maxRequestIdLength {
+		return false
+	}
+	for _, c := range []byte(requestId) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestId() string {
+	b := make([]byte, requestIdBytes)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// contextWithRequestId makes the request ID available to the logs and upstream calls made on its behalf
+func contextWithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdContextKey{}, requestId)
+}
+
+func requestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdContextKey{}).(string)
+	return requestId
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	envVarRetryPolicyFile        = "RETRY_POLICY_FILE"
+	envVarRetryMaxAttempts       = "RETRY_MAX_ATTEMPTS"
+	envVarRetryBaseDelay         = "RETRY_BASE_DELAY"
+	envVarRetryMaxDelay          = "RETRY_MAX_DELAY"
+	envVarRetryJitter            = "RETRY_JITTER"
+	envVarRetryStatusCodes       = "RETRY_STATUS_CODES"
+	envVarRetryRespectRetryAfter = "RETRY_RESPECT_RETRY_AFTER"
+)
+
+// RetryPolicy controls how failed HTTP GET attempts to the Data Source Endpoints are retried.
+// The delay before attempt n+1 is BaseDelay * 2^(n-1), capped at MaxDelay and randomized by
+// +/- Jitter (a fraction between 0 and 1). Transport errors are always retried, HTTP responses
+// only when their status code is part of RetryableStatusCodes.
+type RetryPolicy struct {
+	MaxAttempts          int           `json:"maxAttempts"`
+	BaseDelay            time.Duration `json:"-"`
+	MaxDelay             time.Duration `json:"-"`
+	Jitter               float64       `json:"jitter"`
+	RetryableStatusCodes []int         `json:"retryableStatusCodes"`
+	RespectRetryAfter    bool          `json:"respectRetryAfter"`
+}
+
+// defaultRetryPolicy is used by services created without WithRetryPolicy
+var defaultRetryPolicy = DefaultRetryPolicy()
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+		Jitter:      0.2,
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RespectRetryAfter: true,
+	}
+}
+
+func (p RetryPolicy) isRetryableStatus(statusCode int) bool {
+	for _, retryableStatusCode := range p.RetryableStatusCodes {
+		if statusCode == retryableStatusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns the time to wait after the given failed attempt (starting at 1).
+// A positive retryAfter, taken from the Retry-After header, takes precedence over the backoff.
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	if p.RespectRetryAfter && retryAfter > 0 {
+		return p.capDelay(retryAfter)
+	}
+	backoff := float64(p.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if p.Jitter > 0 {
+		backoff += backoff * p.Jitter * (rand.Float64()*2 - 1)
+	}
+	// Capped before the conversion, as the backoff of late attempts overflows time.Duration
+	if p.MaxDelay > 0 && backoff > float64(p.MaxDelay) {
+		return p.MaxDelay
+	}
+	if backoff >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return p.capDelay(time.Duration(backoff))
+}
+
+func (p RetryPolicy) capDelay(d time.Duration) time.Duration {
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		return p.MaxDelay
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// parseRetryAfter supports both the delay-seconds and the HTTP-date formats
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// retryPolicyFile is the JSON representation of a RetryPolicy, with durations such as "500ms"
+type retryPolicyFile struct {
+	RetryPolicy
+	BaseDelay string `json:"baseDelay"`
+	MaxDelay  string `json:"maxDelay"`
+}
+
+// RetryPolicyFromEnv starts from the default policy, applies the JSON file referenced by
+// RETRY_POLICY_FILE, if any, and then the individual RETRY_* environment variables.
+func RetryPolicyFromEnv(getenv func(string) string) (RetryPolicy, error) {
+	policy := DefaultRetryPolicy()
+
+	if path := getenv(envVarRetryPolicyFile); path != "" {
+		var err error
+		if policy, err = loadRetryPolicyFile(path, policy); err != nil {
+			return policy, err
+		}
+	}
+
+	var err error
+	if value := getenv(envVarRetryMaxAttempts); value != "" {
+		if policy.MaxAttempts, err = strconv.Atoi(value); err != nil {
+			return policy, fmt.Errorf("invalid %s: %w", envVarRetryMaxAttempts, err)
+		}
+	}
+	if value := getenv(envVarRetryBaseDelay); value != "" {
+		if policy.BaseDelay, err = time.ParseDuration(value); err != nil {
+			return policy, fmt.Errorf("invalid %s: %w", envVarRetryBaseDelay, err)
+		}
+	}
+	if value := getenv(envVarRetryMaxDelay); value != "" {
+		if policy.MaxDelay, err = time.ParseDuration(value); err != nil {
+			return policy, fmt.Errorf("invalid %s: %w", envVarRetryMaxDelay, err)
+		}
+	}
+	if value := getenv(envVarRetryJitter); value != "" {
+		if policy.Jitter, err = strconv.ParseFloat(value, 64); err != nil {
+			return policy, fmt.Errorf("invalid %s: %w", envVarRetryJitter, err)
+		}
+	}
+	if value := getenv(envVarRetryStatusCodes); value != "" {
+		if policy.RetryableStatusCodes, err = parseStatusCodes(value); err != nil {
+			return policy, fmt.Errorf("invalid %s: %w", envVarRetryStatusCodes, err)
+		}
+	}
+	if value := getenv(envVarRetryRespectRetryAfter); value != "" {
+		if policy.RespectRetryAfter, err = strconv.ParseBool(value); err != nil {
+			return policy, fmt.Errorf("invalid %s: %w", envVarRetryRespectRetryAfter, err)
+		}
+	}
+	return policy, policy.validate()
+}
+
+func loadRetryPolicyFile(path string, policy RetryPolicy) (RetryPolicy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return policy, err
+	}
+	file := retryPolicyFile{RetryPolicy: policy}
+	if err := json.Unmarshal(content, &file); err != nil {
+		return policy, fmt.Errorf("invalid retry policy file %s: %w", path, err)
+	}
+	policy = file.RetryPolicy
+	if file.BaseDelay != "" {
+		if policy.BaseDelay, err = time.ParseDuration(file.BaseDelay); err != nil {
+			return policy, fmt.Errorf("invalid baseDelay in retry policy file %s: %w", path, err)
+		}
+	}
+	if file.MaxDelay != "" {
+		if policy.MaxDelay, err = time.ParseDuration(file.MaxDelay); err != nil {
+			return policy, fmt.Errorf("invalid maxDelay in retry policy file %s: %w", path, err)
+		}
+	}
+	return policy, nil
+}
+
+func parseStatusCodes(value string) ([]int, error) {
+	var statusCodes []int
+	for _, segment := range strings.Split(value, ",") {
+		statusCode, err := strconv.Atoi(strings.TrimSpace(segment))
+		if err != nil {
+			return nil, err
+		}
+		statusCodes = append(statusCodes, statusCode)
+	}
+	return statusCodes, nil
+}
+
+func (p RetryPolicy) validate() error {
+	if p.MaxAttempts < 1 {
+		return fmt.Errorf("retry policy: maxAttempts must be at least 1. Got: %d", p.MaxAttempts)
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("retry policy: jitter must be between 0 and 1. Got: %v", p.Jitter)
+	}
+	return nil
+}
@@ -0,0 +1,353 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts:       5,
+		BaseDelay:         100 * time.Millisecond,
+		MaxDelay:          time.Second,
+		RespectRetryAfter: true,
+	}
+
+	testCases := []struct {
+		name            string
+		inputPolicy     RetryPolicy
+		inputAttempt    int
+		inputRetryAfter time.Duration
+		expected        time.Duration
+	}{
+		{
+			name:         "first attempt: base delay",
+			inputPolicy:  policy,
+			inputAttempt: 1,
+			expected:     100 * time.Millisecond,
+		},
+		{
+			name:         "third attempt: exponential backoff",
+			inputPolicy:  policy,
+			inputAttempt: 3,
+			expected:     400 * time.Millisecond,
+		},
+		{
+			name:         "tenth attempt: capped at max delay",
+			inputPolicy:  policy,
+			inputAttempt: 10,
+			expected:     time.Second,
+		},
+		{
+			name: "late attempt: capped at max delay, despite overflowing time.Duration",
+			inputPolicy: RetryPolicy{
+				MaxAttempts: 100,
+				BaseDelay:   500 * time.Millisecond,
+				MaxDelay:    10 * time.Second,
+			},
+			inputAttempt: 36,
+			expected:     10 * time.Second,
+		},
+		{
+			name: "last attempt: capped at max delay, despite overflowing time.Duration",
+			inputPolicy: RetryPolicy{
+				MaxAttempts: 100,
+				BaseDelay:   500 * time.Millisecond,
+				MaxDelay:    10 * time.Second,
+			},
+			inputAttempt: 100,
+			expected:     10 * time.Second,
+		},
+		{
+			name: "late attempt without max delay: largest delay",
+			inputPolicy: RetryPolicy{
+				MaxAttempts: 100,
+				BaseDelay:   500 * time.Millisecond,
+			},
+			inputAttempt: 100,
+			expected:     time.Duration(math.MaxInt64),
+		},
+		{
+			name:            "Retry-After takes precedence",
+			inputPolicy:     policy,
+			inputAttempt:    1,
+			inputRetryAfter: 700 * time.Millisecond,
+			expected:        700 * time.Millisecond,
+		},
+		{
+			name:            "Retry-After capped at max delay",
+			inputPolicy:     policy,
+			inputAttempt:    1,
+			inputRetryAfter: time.Minute,
+			expected:        time.Second,
+		},
+		{
+			name: "Retry-After ignored when not respected",
+			inputPolicy: RetryPolicy{
+				MaxAttempts: 5,
+				BaseDelay:   100 * time.Millisecond,
+			},
+			inputAttempt:    1,
+			inputRetryAfter: 700 * time.Millisecond,
+			expected:        100 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result := tc.inputPolicy.delay(tc.inputAttempt, tc.inputRetryAfter)
+			if result != tc.expected {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay_Jitter(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   100 * time.Millisecond,
+		Jitter:      0.5,
+	}
+	for i := 0; i < 100; i++ {
+		result := policy.delay(1, 0)
+		if result < 50*time.Millisecond || result > 150*time.Millisecond {
+			t.Fatalf("Test Failed. Expected delay within [%v, %v]. Actual Result: %v",
+				50*time.Millisecond, 150*time.Millisecond, result)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		input    string
+		expected time.Duration
+	}{
+		{
+			name:     "delay-seconds",
+			input:    "3",
+			expected: 3 * time.Second,
+		},
+		{
+			name:     "HTTP-date",
+			input:    now.Add(10 * time.Second).Format(http.TimeFormat),
+			expected: 10 * time.Second,
+		},
+		{
+			name:     "invalid",
+			input:    "unsupported",
+			expected: 0,
+		},
+		{
+			name:     "empty",
+			expected: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result := parseRetryAfter(tc.input, now)
+			if result != tc.expected {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestGetUrlStatsDataHttp_Retry(t *testing.T) {
+	const inputUrlPath = "/test.json"
+
+	testInputUrlStatData := &types.UrlStatData{
+		Data: []*types.UrlStat{
+			{Url: "www.example.com/abc1", Views: 1000, RelevanceScore: 0.5},
+		},
+	}
+	policy := RetryPolicy{
+		MaxAttempts:          3,
+		BaseDelay:            time.Millisecond,
+		RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+	}
+
+	testCases := []struct {
+		name               string
+		inputFailures      int32
+		inputFailureStatus int
+		expectedRetries    int
+		expectedAttempts   int32
+		expectedErr        bool
+	}{
+		{
+			name:               "retryable status: succeeds after retries",
+			inputFailures:      2,
+			inputFailureStatus: http.StatusServiceUnavailable,
+			expectedRetries:    2,
+			expectedAttempts:   3,
+		},
+		{
+			name:               "retryable status: retry limit exceeded",
+			inputFailures:      5,
+			inputFailureStatus: http.StatusServiceUnavailable,
+			expectedRetries:    2,
+			expectedAttempts:   3,
+			expectedErr:        true,
+		},
+		{
+			name:               "non retryable status: no retries",
+			inputFailures:      1,
+			inputFailureStatus: http.StatusNotFound,
+			expectedRetries:    0,
+			expectedAttempts:   1,
+			expectedErr:        true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var attempts int32
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&attempts, 1) <= tc.inputFailures {
+					w.WriteHeader(tc.inputFailureStatus)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(testInputUrlStatData)
+			}))
+			defer s.Close()
+
+			urlStatService := urlStatDataService{
+				dataSourceType: urlDataSourceHttp,
+				retryPolicy:    &policy,
+			}
+			result, retries, resultErr := urlStatService.getUrlStatsDataHttp(context.Background(), s.URL+inputUrlPath)
+
+			if retries != tc.expectedRetries {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expectedRetries, retries)
+			}
+			if attempts := atomic.LoadInt32(&attempts); attempts != tc.expectedAttempts {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expectedAttempts, attempts)
+			}
+			assertErr := resultErr != nil
+			if assertErr != tc.expectedErr {
+				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
+					tc.name, tc.expectedErr, resultErr)
+			}
+			if !tc.expectedErr && !reflect.DeepEqual(result, testInputUrlStatData) {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, testInputUrlStatData, result)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyFromEnv(t *testing.T) {
+	policyFilePath := filepath.Join(t.TempDir(), "retry-policy.json")
+	policyFileContent := `{"maxAttempts":3,"baseDelay":"1s","maxDelay":"5s","jitter":0,"retryableStatusCodes":[503]}`
+	if err := os.WriteFile(policyFilePath, []byte(policyFileContent), 0644); err != nil {
+		t.Fatalf("Internal Testing error: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		inputEnv    map[string]string
+		expected    RetryPolicy
+		expectedErr bool
+	}{
+		{
+			name:     "no env: default policy",
+			expected: DefaultRetryPolicy(),
+		},
+		{
+			name: "policy file",
+			inputEnv: map[string]string{
+				envVarRetryPolicyFile: policyFilePath,
+			},
+			expected: RetryPolicy{
+				MaxAttempts:          3,
+				BaseDelay:            time.Second,
+				MaxDelay:             5 * time.Second,
+				RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+				RespectRetryAfter:    true,
+			},
+		},
+		{
+			name: "policy file overridden by env",
+			inputEnv: map[string]string{
+				envVarRetryPolicyFile:        policyFilePath,
+				envVarRetryMaxAttempts:       "7",
+				envVarRetryBaseDelay:         "200ms",
+				envVarRetryStatusCodes:       "429, 502",
+				envVarRetryRespectRetryAfter: "false",
+			},
+			expected: RetryPolicy{
+				MaxAttempts:          7,
+				BaseDelay:            200 * time.Millisecond,
+				MaxDelay:             5 * time.Second,
+				RetryableStatusCodes: []int{http.StatusTooManyRequests, http.StatusBadGateway},
+			},
+		},
+		{
+			name: "invalid max attempts",
+			inputEnv: map[string]string{
+				envVarRetryMaxAttempts: "0",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid jitter",
+			inputEnv: map[string]string{
+				envVarRetryJitter: "2",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "missing policy file",
+			inputEnv: map[string]string{
+				envVarRetryPolicyFile: filepath.Join(t.TempDir(), "missing.json"),
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result, resultErr := RetryPolicyFromEnv(func(key string) string {
+				return tc.inputEnv[key]
+			})
+			assertErr := resultErr != nil
+			if assertErr != tc.expectedErr {
+				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
+					tc.name, tc.expectedErr, resultErr)
+			}
+			if !tc.expectedErr && !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %+v Actual Result: %+v",
+					tc.name, tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	apiVersionPrefix = "/v1"
+	sortKeyPathValue = "key"
+
+	// unmatchedRoute labels the metrics of requests no route matched
+	unmatchedRoute = "unmatched"
+)
+
+type route struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+}
+
+// pattern is the ServeMux pattern, e.g. "GET /sortkey/{key}". GET routes also match HEAD.
+func (rt route) pattern() string {
+	return rt.method + " " + rt.path
+}
+
+// label is the path without the end anchor, used to label metrics, e.g. "/" for "/{$}"
+func (rt route) label() string {
+	if label := strings.TrimSuffix(rt.path, "{$}"); label != "" {
+		return label
+	}
+	return rt.path
+}
+
+// routes serves the API under apiVersionPrefix and, as aliases for legacy clients, at the root
+func (s *apiServer) routes() []route {
+	var routes []route
+	for _, prefix := range []string{apiVersionPrefix, ""} {
+		routes = append(routes,
+			route{method: http.MethodGet, path: prefix + "/{$}", handler: middlewareHandler(s.handleRawStats)},
+			route{method: http.MethodGet, path: fmt.Sprintf("%s/%s/{%s}", prefix, sortkeyPath, sortKeyPathValue), handler: middlewareHandler(s.handleSortKey)},
+		)
+	}
+	return append(routes,
+		route{method: http.MethodGet, path: metricsPath, handler: s.handleMetrics},
+		route{method: http.MethodGet, path: healthzPath, handler: s.handleHealthz},
+		route{method: http.MethodGet, path: readyzPath, handler: s.handleReadyz},
+		route{method: http.MethodGet, path: openApiPath, handler: s.handleOpenApi},
+	)
+}
+
+func (s *apiServer) newRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, rt := range s.routes() {
+		mux.HandleFunc(rt.pattern(), s.instrumentHandler(rt.label(), s.compressHandler(rt.handler)))
+	}
+	return mux
+}
+
+// ServeHTTP answers the requests no route matched with a problem+json 404, or a 405
+// listing the allowed methods in the Allow header when the path matches another method
+func (s *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h, pattern := s.mux.Handler(r); pattern == "" {
+		s.instrumentHandler(unmatchedRoute, s.compressHandler(middlewareHandler(newRoutingErrorHandler(h))))(w, r)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// routingErrorRecorder captures the status and headers written by the ServeMux
+// 404 and 405 handlers, discarding their plain text body
+type routingErrorRecorder struct {
+	header     http.Header
+	statusCode int
+}
+
+func (r *routingErrorRecorder) Header() http.Header         { return r.header }
+func (r *routingErrorRecorder) Write(b []byte) (int, error) { return len(b), nil }
+func (r *routingErrorRecorder) WriteHeader(statusCode int)  { r.statusCode = statusCode }
+
+func newRoutingErrorHandler(h http.Handler) customHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *handlerResponse {
+		rec := &routingErrorRecorder{header: http.Header{}}
+		h.ServeHTTP(rec, r)
+		if rec.statusCode == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", rec.header.Get("Allow"))
+			return &handlerResponse{
+				Err:        fmt.Errorf("method %s is not allowed on %s", r.Method, r.URL.Path),
+				StatusCode: http.StatusMethodNotAllowed,
+				Code:       errCodeMethodNotAllowed}
+		}
+		return &handlerResponse{
+			Err:        errors.New(http.StatusText(http.StatusNotFound)),
+			StatusCode: http.StatusNotFound,
+			Code:       errCodeNotFound}
+	}
+}
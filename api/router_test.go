@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+func TestApiServerRouting(t *testing.T) {
+	testCases := []struct {
+		name               string
+		inputMethod        string
+		inputPath          string
+		expectedStatusCode int
+		expectedCode       string
+		expectedAllow      string
+	}{
+		{
+			name:               "GET /",
+			inputMethod:        http.MethodGet,
+			inputPath:          "/",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "HEAD /sortkey/views",
+			inputMethod:        http.MethodHead,
+			inputPath:          "/sortkey/views",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "GET /v1/",
+			inputMethod:        http.MethodGet,
+			inputPath:          "/v1/",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "GET /v1/sortkey/views:desc",
+			inputMethod:        http.MethodGet,
+			inputPath:          "/v1/sortkey/views:desc",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "GET /healthz",
+			inputMethod:        http.MethodGet,
+			inputPath:          healthzPath,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "unknown path",
+			inputMethod:        http.MethodGet,
+			inputPath:          "/unsupported",
+			expectedStatusCode: http.StatusNotFound,
+			expectedCode:       errCodeNotFound,
+		},
+		{
+			name:               "nested sort key path",
+			inputMethod:        http.MethodGet,
+			inputPath:          "/sortkey/views/url",
+			expectedStatusCode: http.StatusNotFound,
+			expectedCode:       errCodeNotFound,
+		},
+		{
+			name:               "POST /",
+			inputMethod:        http.MethodPost,
+			inputPath:          "/",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedCode:       errCodeMethodNotAllowed,
+			expectedAllow:      "GET, HEAD",
+		},
+		{
+			name:               "PUT /sortkey/views",
+			inputMethod:        http.MethodPut,
+			inputPath:          "/sortkey/views",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedCode:       errCodeMethodNotAllowed,
+			expectedAllow:      "GET, HEAD",
+		},
+		{
+			name:               "unversioned probe",
+			inputMethod:        http.MethodGet,
+			inputPath:          "/v1" + healthzPath,
+			expectedStatusCode: http.StatusNotFound,
+			expectedCode:       errCodeNotFound,
+		},
+		{
+			name:               "PUT /v1/sortkey/views",
+			inputMethod:        http.MethodPut,
+			inputPath:          "/v1/sortkey/views",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedCode:       errCodeMethodNotAllowed,
+			expectedAllow:      "GET, HEAD",
+		},
+		{
+			name:               "DELETE /metrics",
+			inputMethod:        http.MethodDelete,
+			inputPath:          metricsPath,
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedCode:       errCodeMethodNotAllowed,
+			expectedAllow:      "GET, HEAD",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			apiServer := NewApiServer(&stubService{data: newStubUrlStatData()})
+			rec := httptest.NewRecorder()
+			apiServer.ServeHTTP(rec, httptest.NewRequest(tc.inputMethod, tc.inputPath, nil))
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expectedStatusCode, rec.Code)
+			}
+			if allow := rec.Header().Get("Allow"); allow != tc.expectedAllow {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expectedAllow, allow)
+			}
+			if tc.expectedCode == "" {
+				return
+			}
+			if contentType := rec.Header().Get("Content-Type"); contentType != contentTypeProblemJson {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, contentTypeProblemJson, contentType)
+			}
+			errResp := new(types.ErrorResponse)
+			if err := json.NewDecoder(rec.Body).Decode(errResp); err != nil {
+				t.Fatalf("Test Failed: %v. Failed to decode response. Error: %v", tc.name, err)
+			}
+			if errResp.Code != tc.expectedCode || errResp.Status != tc.expectedStatusCode {
+				t.Fatalf("Test Failed: %v. Expected Result: %v %v Actual Result: %v %v",
+					tc.name, tc.expectedStatusCode, tc.expectedCode, errResp.Status, errResp.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	envVarServerReadTimeout   = "SERVER_READ_TIMEOUT"
+	envVarServerWriteTimeout  = "SERVER_WRITE_TIMEOUT"
+	envVarServerIdleTimeout   = "SERVER_IDLE_TIMEOUT"
+	envVarShutdownDrainPeriod = "SHUTDOWN_DRAIN_PERIOD"
+	envVarShutdownTimeout     = "SHUTDOWN_TIMEOUT"
+
+	defaultServerReadTimeout   = 10 * time.Second
+	defaultServerWriteTimeout  = 45 * time.Second
+	defaultServerIdleTimeout   = 120 * time.Second
+	defaultShutdownDrainPeriod = 5 * time.Second
+	defaultShutdownTimeout     = 20 * time.Second
+)
+
+// ServerConfig holds the timeouts of the HTTP server. The write timeout must leave room
+// for a request triggering the first data load, which is bound by DATA_FETCH_TIMEOUT.
+// On shutdown, the server reports not ready for DrainPeriod, so load balancers stop
+// routing to it, and then waits up to ShutdownTimeout for in-flight requests.
+// Zero timeouts fall back to the defaults, while a zero DrainPeriod skips draining.
+type ServerConfig struct {
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	DrainPeriod     time.Duration
+	ShutdownTimeout time.Duration
+}
+
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{
+		ReadTimeout:     defaultServerReadTimeout,
+		WriteTimeout:    defaultServerWriteTimeout,
+		IdleTimeout:     defaultServerIdleTimeout,
+		DrainPeriod:     defaultShutdownDrainPeriod,
+		ShutdownTimeout: defaultShutdownTimeout,
+	}
+}
+
+// ServerConfigFromEnv starts from the default config and applies the SERVER_* and SHUTDOWN_* environment variables
+func ServerConfigFromEnv(getenv func(string) string) (ServerConfig, error) {
+	config := DefaultServerConfig()
+	for _, setting := range []struct {
+		envVar string
+		value  *time.Duration
+	}{
+		{envVarServerReadTimeout, &config.ReadTimeout},
+		{envVarServerWriteTimeout, &config.WriteTimeout},
+		{envVarServerIdleTimeout, &config.IdleTimeout},
+		{envVarShutdownDrainPeriod, &config.DrainPeriod},
+		{envVarShutdownTimeout, &config.ShutdownTimeout},
+	} {
+		value := getenv(setting.envVar)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid %s: %w", setting.envVar, err)
+		}
+		*setting.value = d
+	}
+	return config, config.validate()
+}
+
+func (c ServerConfig) validate() error {
+	if c.ReadTimeout < 0 || c.WriteTimeout < 0 || c.IdleTimeout < 0 || c.DrainPeriod < 0 || c.ShutdownTimeout < 0 {
+		return fmt.Errorf("server: timeouts must not be negative. Got: %+v", c)
+	}
+	return nil
+}
+
+// newHttpServer applies the configured timeouts. Zero values fall back to the defaults.
+// The write timeout is also passed to the requests, for streamed responses to extend it.
+func (s *apiServer) newHttpServer(listenAddr string, handler http.Handler) *http.Server {
+	readTimeout := durationOrDefault(s.config.ReadTimeout, defaultServerReadTimeout)
+	writeTimeout := durationOrDefault(s.config.WriteTimeout, defaultServerWriteTimeout)
+	return &http.Server{
+		Addr:              listenAddr,
+		Handler:           handler,
+		ReadTimeout:       readTimeout,
+		ReadHeaderTimeout: readTimeout / 2,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       durationOrDefault(s.config.IdleTimeout, defaultServerIdleTimeout),
+		BaseContext: func(net.Listener) context.Context {
+			return contextWithWriteTimeout(context.Background(), writeTimeout)
+		},
+	}
+}
+
+// serve listens synchronously, so that errors such as the address being in use are returned,
+// and then serves in the background. Later serving errors are sent on the returned channel.
+// srv.Addr is updated with the address listened on, e.g. the port picked for ":0".
+func serve(srv *http.Server) (<-chan error, error) {
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return nil, err
+	}
+	srv.Addr = ln.Addr().String()
+	serveErr := make(chan error, 1)
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+	slog.Info("Server started", "addr", srv.Addr)
+	return serveErr, nil
+}
+
+// Shutdown reports not ready, waits for the drain period, stops accepting connections
+// and waits for the in-flight requests, up to the shutdown timeout. Connections still
+// open after the timeout are closed. The background data refreshes are stopped last.
+func (s *apiServer) Shutdown(ctx context.Context, srv *http.Server) error {
+	s.draining.Store(true)
+
+	drainPeriod := s.config.DrainPeriod
+	slog.InfoContext(ctx, "Shutting down. Draining", "drainPeriod", drainPeriod)
+	select {
+	case <-time.After(drainPeriod):
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, durationOrDefault(s.config.ShutdownTimeout, defaultShutdownTimeout))
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	if err != nil {
+		slog.WarnContext(ctx, "In-flight requests did not complete in time. Closing connections", "error", err)
+		srv.Close()
+	}
+
+	if s.snapshots != nil {
+		s.snapshots.stop()
+	}
+	slog.InfoContext(ctx, "Shutdown complete")
+	return err
+}
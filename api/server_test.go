@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestServerConfigFromEnv(t *testing.T) {
+	testCases := []struct {
+		name        string
+		inputEnv    map[string]string
+		expected    ServerConfig
+		expectedErr bool
+	}{
+		{
+			name:     "no env: default config",
+			expected: DefaultServerConfig(),
+		},
+		{
+			name: "overridden timeouts",
+			inputEnv: map[string]string{
+				envVarServerWriteTimeout:  "1m",
+				envVarShutdownDrainPeriod: "0s",
+				envVarShutdownTimeout:     "5s",
+			},
+			expected: ServerConfig{
+				ReadTimeout:     defaultServerReadTimeout,
+				WriteTimeout:    time.Minute,
+				IdleTimeout:     defaultServerIdleTimeout,
+				DrainPeriod:     0,
+				ShutdownTimeout: 5 * time.Second,
+			},
+		},
+		{
+			name: "invalid duration",
+			inputEnv: map[string]string{
+				envVarServerReadTimeout: "10",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "negative duration",
+			inputEnv: map[string]string{
+				envVarShutdownDrainPeriod: "-1s",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result, resultErr := ServerConfigFromEnv(func(key string) string {
+				return tc.inputEnv[key]
+			})
+			assertErr := resultErr != nil
+			if assertErr != tc.expectedErr {
+				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
+					tc.name, tc.expectedErr, resultErr)
+			}
+			if !tc.expectedErr && !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %+v Actual Result: %+v",
+					tc.name, tc.expected, result)
+			}
+		})
+	}
+}
+
+// newShutdownTestServer serves /readyz and a /slow route blocking until release is closed
+func newShutdownTestServer(t *testing.T, config ServerConfig) (*apiServer, *http.Server, chan struct{}, chan struct{}) {
+	t.Helper()
+	svc := NewCachingService(context.Background(), &stubService{data: newStubUrlStatData()}, time.Hour)
+	if _, err := svc.getUrlStatsData(context.Background()); err != nil {
+		t.Fatalf("Internal Testing error: %v", err)
+	}
+	apiServer := NewApiServer(svc, WithServerConfig(config))
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc(readyzPath, apiServer.handleReadyz)
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+
+	srv := apiServer.newHttpServer("127.0.0.1:0", mux)
+	if _, err := serve(srv); err != nil {
+		t.Fatalf("Internal Testing error: %v", err)
+	}
+	return apiServer, srv, entered, release
+}
+
+func TestApiServerShutdown(t *testing.T) {
+	apiServer, srv, entered, release := newShutdownTestServer(t, ServerConfig{
+		DrainPeriod:     200 * time.Millisecond,
+		ShutdownTimeout: 5 * time.Second,
+	})
+
+	slowStatus := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + srv.Addr + "/slow")
+		if err != nil {
+			slowStatus <- 0
+			return
+		}
+		resp.Body.Close()
+		slowStatus <- resp.StatusCode
+	}()
+	<-entered
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- apiServer.Shutdown(context.Background(), srv)
+	}()
+
+	// Still accepting connections while draining, but reporting not ready
+	time.Sleep(50 * time.Millisecond)
+	resp, err := http.Get("http://" + srv.Addr + readyzPath)
+	if err != nil {
+		t.Fatalf("Test Failed. Expected connections to be accepted while draining. Error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	close(release)
+	if status := <-slowStatus; status != http.StatusOK {
+		t.Fatalf("Test Failed. Expected the in-flight request to complete. Expected Result: %v Actual Result: %v",
+			http.StatusOK, status)
+	}
+	if err := <-shutdownErr; err != nil {
+		t.Fatalf("Test Failed. Unexpected Error: %v", err)
+	}
+	select {
+	case <-apiServer.svc.(*cachingService).done:
+	default:
+		t.Fatalf("Test Failed. Expected the refresh loop to be stopped")
+	}
+	if _, err := http.Get("http://" + srv.Addr + readyzPath); err == nil {
+		t.Fatalf("Test Failed. Expected the server to be closed")
+	}
+}
+
+func TestApiServerShutdown_Timeout(t *testing.T) {
+	apiServer, srv, entered, release := newShutdownTestServer(t, ServerConfig{
+		ShutdownTimeout: 50 * time.Millisecond,
+	})
+	defer close(release)
+
+	slowErr := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + srv.Addr + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+		slowErr <- err
+	}()
+	<-entered
+
+	if err := apiServer.Shutdown(context.Background(), srv); err == nil {
+		t.Fatalf("Test Failed. Expected Error to occur: true. Returned Error: %v", err)
+	}
+	if err := <-slowErr; err == nil {
+		t.Fatalf("Test Failed. Expected the in-flight request to be cut off")
+	}
+}
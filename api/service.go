@@ -1,16 +1,18 @@
 package api
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/felipe88alves/sortKeyHttpServer/types"
@@ -26,7 +28,12 @@ const (
 
 	relevancescoreOption = "relevanceScore"
 	viewsOption          = "views"
+	urlOption            = "url"
 	limitFilterOption    = "limit"
+	offsetFilterOption   = "offset"
+	cursorFilterOption   = "cursor"
+	strictFilterOption   = "strict"
+	strictSourcesOption  = "strictSources"
 )
 
 var (
@@ -41,14 +48,82 @@ var (
 		urlDataSourceFile: fileTypeJson,
 	}
 
-	retryAttempts  = 5
-	backoffPeriods = []time.Duration{
-		1 * time.Second,
-		5 * time.Second,
-		10 * time.Second,
-	}
+	defaultFetchTimeout   = 30 * time.Second
+	defaultAttemptTimeout = 10 * time.Second
 )
 
+const (
+	envVarRefreshInterval       = "DATA_REFRESH_INTERVAL"
+	envVarFetchTimeout          = "DATA_FETCH_TIMEOUT"
+	envVarAttemptTimeout        = "DATA_FETCH_ATTEMPT_TIMEOUT"
+	envVarMaxConcurrency        = "DATA_FETCH_MAX_CONCURRENCY"
+	envVarMaxConcurrencyPerHost = "DATA_FETCH_MAX_CONCURRENCY_PER_HOST"
+	envVarMaxSnapshotAge        = "READINESS_MAX_SNAPSHOT_AGE"
+)
+
+// DataConfig holds how the data is fetched and refreshed, and the snapshot age past which
+// the server reports not ready. Zero values fall back to the defaults.
+type DataConfig struct {
+	RefreshInterval       time.Duration
+	FetchTimeout          time.Duration
+	AttemptTimeout        time.Duration
+	MaxConcurrency        int
+	MaxConcurrencyPerHost int
+	MaxSnapshotAge        time.Duration
+}
+
+// DataConfigFromEnv reads the DATA_* and READINESS_* environment variables
+func DataConfigFromEnv(getenv func(string) string) (DataConfig, error) {
+	var config DataConfig
+	for _, setting := range []struct {
+		envVar string
+		value  *time.Duration
+	}{
+		{envVarRefreshInterval, &config.RefreshInterval},
+		{envVarFetchTimeout, &config.FetchTimeout},
+		{envVarAttemptTimeout, &config.AttemptTimeout},
+		{envVarMaxSnapshotAge, &config.MaxSnapshotAge},
+	} {
+		value := getenv(setting.envVar)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid %s: %w", setting.envVar, err)
+		}
+		*setting.value = d
+	}
+	for _, setting := range []struct {
+		envVar string
+		value  *int
+	}{
+		{envVarMaxConcurrency, &config.MaxConcurrency},
+		{envVarMaxConcurrencyPerHost, &config.MaxConcurrencyPerHost},
+	} {
+		value := getenv(setting.envVar)
+		if value == "" {
+			continue
+		}
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid %s: %w", setting.envVar, err)
+		}
+		*setting.value = i
+	}
+	return config, config.validate()
+}
+
+func (c DataConfig) validate() error {
+	if c.RefreshInterval < 0 || c.FetchTimeout < 0 || c.AttemptTimeout < 0 || c.MaxSnapshotAge < 0 {
+		return fmt.Errorf("data: durations must not be negative. Got: %+v", c)
+	}
+	if c.MaxConcurrency < 0 || c.MaxConcurrencyPerHost < 0 {
+		return fmt.Errorf("data: concurrency limits must not be negative. Got: %+v", c)
+	}
+	return nil
+}
+
 type service interface {
 	getUrlStatsData(context.Context) (*types.UrlStatData, error)
 }
@@ -56,9 +131,24 @@ type service interface {
 type urlStatDataService struct {
 	dataSourceType string
 	dataSourcePath string
+
+	// Zero values fall back to the package defaults
+	fetchTimeout   time.Duration
+	attemptTimeout time.Duration
+	retryPolicy    *RetryPolicy
+
+	maxConcurrency        int
+	maxConcurrencyPerHost int
+	httpClient            *http.Client
+
+	validation ValidationConfig
+	merge      MergeConfig
+	normalize  NormalizeConfig
+
+	metrics *serverMetrics
 }
 
-func NewUrlStatDataService(dataSourceType string, dataSourcePath string) (service, error) {
+func NewUrlStatDataService(dataSourceType string, dataSourcePath string, opts ...ServiceOption) (service, error) {
 	var err error
 
 	dataSourceType = getDataSourceType(dataSourceType)
@@ -67,13 +157,19 @@ func NewUrlStatDataService(dataSourceType string, dataSourcePath string) (servic
 		return nil, err
 	}
 
-	return &urlStatDataService{
+	uS := &urlStatDataService{
 		dataSourceType: dataSourceType,
 		dataSourcePath: dataSourcePath,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(uS)
+	}
+	return uS, nil
 }
 
 func (uS *urlStatDataService) getUrlStatsData(ctx context.Context) (*types.UrlStatData, error) {
+	ctx, cancel := context.WithTimeout(ctx, durationOrDefault(uS.fetchTimeout, defaultFetchTimeout))
+	defer cancel()
 
 	switch uS.dataSourceType {
 	case urlDataSourceHttp:
@@ -81,7 +177,7 @@ func (uS *urlStatDataService) getUrlStatsData(ctx context.Context) (*types.UrlSt
 	case urlDataSourceFile:
 		return uS.getUrlStatsDataFromFile(ctx)
 	default:
-		return nil, fmt.Errorf("invalid method for getting json data. Data Source: %s", uS.dataSourceType)
+		return nil, &dataSourceConfigError{fmt.Errorf("invalid method for getting json data. Data Source: %s", uS.dataSourceType)}
 	}
 }
 
@@ -91,10 +187,10 @@ func getDataSourceType(dataSourceType string) string {
 	case urlDataSourceHttp:
 		return dataSourceType
 	case urlDataSourceFile:
-		log.Printf("WARNING: Do not use this setting in production. Overriding Data Source to custom value: %v", urlDataSourceFile)
+		slog.Warn("Do not use this setting in production. Overriding Data Source to custom value", "dataSource", urlDataSourceFile)
 		return dataSourceType
 	default:
-		log.Printf("WARNING: Invalid Data Source Selected: %v. Using default value: %v", dataSourceType, urlDataSourceHttp)
+		slog.Warn("Invalid Data Source selected. Using default value", "dataSource", dataSourceType, "default", urlDataSourceHttp)
 		return urlDataSourceHttp
 	}
 }
@@ -115,128 +211,250 @@ func getDataSource(dataSourceType, dataSourcePath string) (string, error) {
 	return dataSourcePath, nil
 }
 
+type upstreamResult struct {
+	data   *types.UrlStatData
+	status *types.SourceStatus
+	err    error
+}
+
 func (uS *urlStatDataService) getUrlStatsDataHttpEndpointsFromFile(ctx context.Context) (*types.UrlStatData, error) {
 	files, err := utils.GetFilesInRelativePathByType(uS.dataSourcePath, fileTypeCfg)
 	if err != nil {
-		return nil, err
+		return nil, &dataSourceConfigError{err}
 	}
 
-	urlStats := new(types.UrlStatData)
-	errCount := 0
-
-	ch := make(chan interface{})
-	var wg sync.WaitGroup
-
+	var urlAddrs []string
 	for _, file := range files {
 		relativeFilePath := filepath.Join(uS.dataSourcePath, file.Name())
 		fileName, err := utils.MustGetFile(relativeFilePath)
 		if err != nil {
-			return nil, err
+			return nil, &dataSourceConfigError{err}
 		}
 		urls := strings.Split(string(fileName), "\n")
 		urls, err = validateUrls(urls)
 		if err != nil {
-			return nil, err
-		}
-
-		for _, urlAddr := range urls {
-			wg.Add(1)
-			go func(urlAddr string, ch chan<- interface{}, wg *sync.WaitGroup) {
-				defer wg.Done()
-				urlData, err := getUrlStatsDataHttp(urlAddr)
-				if err != nil {
-					ch <- err
-				} else {
-					ch <- urlData
-				}
-			}(urlAddr, ch, &wg)
+			return nil, &dataSourceConfigError{err}
 		}
+		urlAddrs = append(urlAddrs, urls...)
 	}
 
-	go func() {
-		wg.Wait()
-		close(ch)
-	}()
-
-	for rCh := range ch {
-		switch r := rCh.(type) {
-		case error:
-			log.Printf("Error: %v", r)
-			errCount++
-		case *types.UrlStatData:
-			if r != nil {
-				urlStats.Data = append(urlStats.Data, r.Data...)
-			}
-		default:
-			log.Print("Error: HTTP Data Source Endpoint returned an Unsupported Type")
-			errCount++
+	urlStats := new(types.UrlStatData)
+	var errs []error
+	var batches []sourceBatch
+	for _, r := range uS.fetchAll(ctx, urlAddrs) {
+		urlStats.Sources = append(urlStats.Sources, r.status)
+		if r.err != nil {
+			slog.WarnContext(ctx, "Failed to load Data Source Endpoint", "source", r.status.Source, "error", r.err)
+			errs = append(errs, r.err)
+			continue
 		}
+		batches = append(batches, sourceBatch{source: r.status.Source, data: r.data.Data})
 	}
+	urlStats.Data = uS.aggregate(batches)
+	sort.Slice(urlStats.Sources, func(i, j int) bool {
+		return urlStats.Sources[i].Source < urlStats.Sources[j].Source
+	})
 
 	if len(urlStats.Data) == 0 {
-		return nil, fmt.Errorf("all %v http get attempts failed", errCount)
+		return nil, &upstreamUnavailableError{errs: errs, sources: urlStats.Sources}
 	}
 	return urlStats, nil
 }
 
-func getUrlStatsDataHttp(urlAddr string) (*types.UrlStatData, error) {
-	var (
-		r       *http.Response
-		success bool
-		err     error
-	)
-
-retry_loop:
-	for _, backoff := range backoffPeriods {
-		for i := 0; i < retryAttempts; i++ {
-			r, err = http.Get(urlAddr)
-			if err != nil {
-				log.Printf("Failed to HTTP GET %v. Retrying in %s", urlAddr, backoff)
-				time.Sleep(backoff)
-				continue
-			}
-			success = true
-			break retry_loop
+// aggregate normalizes the urls of every source and merges the duplicates
+func (uS *urlStatDataService) aggregate(batches []sourceBatch) types.UrlStatSlice {
+	for _, batch := range batches {
+		uS.normalize.normalizeUrlStats(batch.data)
+	}
+	return uS.merge.mergeDuplicates(batches)
+}
+
+func (uS *urlStatDataService) getUrlStatsDataHttpWithStatus(ctx context.Context, urlAddr string) *upstreamResult {
+	start := time.Now()
+	urlData, retries, err := uS.getUrlStatsDataHttp(ctx, urlAddr)
+
+	var report *validationReport
+	if err == nil {
+		urlData, report, err = uS.validation.validate(urlAddr, urlData)
+		if err != nil {
+			err = &upstreamError{Url: urlAddr, Err: err}
 		}
 	}
-	if !success {
-		return nil, fmt.Errorf("ERROR: Retry limit exceeded. Failed to HTTP GET %v", urlAddr)
+	result := newUpstreamResult(urlAddr, urlData, retries, time.Since(start), err)
+	report.apply(result.status)
+	uS.getMetrics().observeSource(result.status)
+	return result
+}
+
+func newUpstreamResult(urlAddr string, urlData *types.UrlStatData, retries int, took time.Duration, err error) *upstreamResult {
+	status := &types.SourceStatus{
+		Source:     urlAddr,
+		Success:    err == nil,
+		Retries:    retries,
+		DurationMs: float64(took.Microseconds()) / 1000,
+	}
+	if err != nil {
+		status.Error = err.Error()
+		var upstreamErr *upstreamError
+		if errors.As(err, &upstreamErr) {
+			status.StatusCode = upstreamErr.StatusCode
+		}
+	} else {
+		status.Records = len(urlData.Data)
+	}
+	return &upstreamResult{data: urlData, status: status, err: err}
+}
+
+func (uS *urlStatDataService) getRetryPolicy() RetryPolicy {
+	if uS.retryPolicy == nil {
+		return defaultRetryPolicy
 	}
+	return *uS.retryPolicy
+}
+
+// getUrlStatsDataHttp also returns the number of retries performed, regardless of the outcome
+func (uS *urlStatDataService) getUrlStatsDataHttp(ctx context.Context, urlAddr string) (*types.UrlStatData, int, error) {
+	policy := uS.getRetryPolicy()
+
+	for attempt := 1; ; attempt++ {
+		urlStats, retryAfter, err := uS.getUrlStatsDataHttpAttempt(ctx, urlAddr)
+		retries := attempt - 1
+		if err == nil || !isRetryableError(err, policy) {
+			return urlStats, retries, err
+		}
+		if ctx.Err() != nil {
+			return nil, retries, &upstreamError{Url: urlAddr, Err: ctx.Err()}
+		}
+		if attempt >= policy.MaxAttempts {
+			return nil, retries, retryLimitExceeded(urlAddr, err)
+		}
+
+		delay := policy.delay(attempt, retryAfter)
+		slog.WarnContext(ctx, "Failed to HTTP GET Data Source Endpoint. Retrying",
+			"source", urlAddr, "attempt", attempt, "maxAttempts", policy.MaxAttempts, "delay", delay, "error", err)
+		if err := sleepWithContext(ctx, delay); err != nil {
+			return nil, retries, &upstreamError{Url: urlAddr, Err: err}
+		}
+	}
+}
+
+// getUrlStatsDataHttpAttempt returns the delay requested by the Retry-After header, if any
+func (uS *urlStatDataService) getUrlStatsDataHttpAttempt(ctx context.Context, urlAddr string) (*types.UrlStatData, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, durationOrDefault(uS.attemptTimeout, defaultAttemptTimeout))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlAddr, nil)
+	if err != nil {
+		return nil, 0, &upstreamError{Url: urlAddr, Err: err}
+	}
+	if requestId := requestIdFromContext(ctx); requestId != "" {
+		req.Header.Set(requestIdHeader, requestId)
+	}
+	r, err := uS.getHttpClient().Do(req)
+	if err != nil {
+		return nil, 0, &transportError{err: err}
+	}
+	defer r.Body.Close()
+
 	statusOK := r.StatusCode >= 200 && r.StatusCode < 300
 	if !statusOK {
-		return nil, fmt.Errorf("HTTP Get to %v Failed. HTTP Response: %d - %s", urlAddr, r.StatusCode, http.StatusText(r.StatusCode))
+		return nil, parseRetryAfter(r.Header.Get("Retry-After"), time.Now()),
+			&upstreamError{Url: urlAddr, StatusCode: r.StatusCode}
 	}
 
-	defer r.Body.Close()
-	urlStats := new(types.UrlStatData)
-	json.NewDecoder(r.Body).Decode(urlStats)
-	return urlStats, nil
+	urlStats, err := decodeUrlStatData(r.Body, uS.validation.DisallowUnknownFields)
+	if err != nil {
+		return nil, 0, &upstreamError{Url: urlAddr, Err: err}
+	}
+	return urlStats, 0, nil
+}
+
+// transportError marks attempts that failed before an HTTP response was received
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+func isRetryableError(err error, policy RetryPolicy) bool {
+	var transportErr *transportError
+	if errors.As(err, &transportErr) {
+		return true
+	}
+	var upstreamErr *upstreamError
+	if errors.As(err, &upstreamErr) && upstreamErr.StatusCode != 0 {
+		return policy.isRetryableStatus(upstreamErr.StatusCode)
+	}
+	return false
+}
+
+func retryLimitExceeded(urlAddr string, err error) error {
+	var upstreamErr *upstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr
+	}
+	return &upstreamError{Url: urlAddr, Err: fmt.Errorf("retry limit exceeded: %w", err)}
+}
+
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func durationOrDefault(d, defaultDuration time.Duration) time.Duration {
+	if d <= 0 {
+		return defaultDuration
+	}
+	return d
 }
 
 func (uS *urlStatDataService) getUrlStatsDataFromFile(ctx context.Context) (*types.UrlStatData, error) {
 	files, err := utils.GetFilesInRelativePathByType(uS.dataSourcePath, fileTypeJson)
 	if err != nil {
-		return nil, err
+		return nil, &dataSourceConfigError{err}
 	}
 
 	urlStats := new(types.UrlStatData)
+	var batches []sourceBatch
 
 	for _, file := range files {
 		relativeFilePath := filepath.Join(uS.dataSourcePath, file.Name())
 		fileName, err := utils.MustGetFile(relativeFilePath)
 		if err != nil {
-			return nil, err
+			return nil, &dataSourceConfigError{err}
+		}
+
+		// Files failing to load are reported in Sources, the remaining files are still served
+		var report *validationReport
+		urlStatsInstance, err := decodeUrlStatData(bytes.NewReader(fileName), uS.validation.DisallowUnknownFields)
+		if err == nil {
+			urlStatsInstance, report, err = uS.validation.validate(file.Name(), urlStatsInstance)
 		}
-		urlStatsInstance := types.UrlStatData{}
-		if err := json.Unmarshal(fileName, &urlStatsInstance); err != nil {
-			log.Printf("Failed to unmarshal json data from file-based source. File: %v Error: %v", relativeFilePath, err)
-			// TODO: Investigate: Should we allow the program to continue if one files fails to be loaded?
+		result := newUpstreamResult(file.Name(), urlStatsInstance, 0, 0, err)
+		report.apply(result.status)
+		uS.getMetrics().observeSource(result.status)
+		urlStats.Sources = append(urlStats.Sources, result.status)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to load json data from file-based source", "file", relativeFilePath, "error", err)
 			continue
 		}
-		urlStats.Data = append(urlStats.Data, urlStatsInstance.Data...)
+		batches = append(batches, sourceBatch{source: file.Name(), data: urlStatsInstance.Data})
 	}
+	urlStats.Data = uS.aggregate(batches)
 	if len(urlStats.Data) == 0 {
-		return nil, fmt.Errorf("no valid JSON data was found within the configured Data Source files")
+		return nil, &dataSourceConfigError{fmt.Errorf("no valid JSON data was found within the configured Data Source files")}
 	}
 
 	return urlStats, nil
@@ -264,113 +482,80 @@ func isValidUrlSuffixFileType(url string) bool {
 	return strings.HasSuffix(url, fileTypeJson)
 }
 
-func mergeSort(items *types.UrlStatSlice, sortBy string) (*types.UrlStatSlice, error) {
+// sortByKeys returns a sorted copy of items, leaving items untouched. The sort is stable, so
+// records comparing equal on every key keep their input order.
+func sortByKeys(items *types.UrlStatSlice, keys sortKeys) (*types.UrlStatSlice, error) {
 	if items == nil {
 		return nil, fmt.Errorf("null pointer exception. Found when sorting Url Data")
 	}
-	sortBy = getSortOption(sortBy)
-
-	if len(*items) <= 1 {
-		return items, nil
-	}
-	first := (*items)[:len(*items)/2]
-	firstPtr, err := mergeSort(&first, sortBy)
-	if err != nil {
-		return nil, err
-	}
-	second := (*items)[len(*items)/2:]
-	secondPtr, err := mergeSort(&second, sortBy)
-	// The error handling below is unecessary wih current implementation. Decision was made to leave it in for robustness.
-	// DETAILS ON DECISION: mergeSort error is caused by sortOption, so it would have already been returned in the first call to mergeSort.
-	// This comment block can be removed if additional error handling is added to mergeSort
+	sorted := slices.Clone(*items)
+	// Comparison errors can not be returned through the comparator and are kept in err
+	var err error
+	slices.SortStableFunc(sorted, func(first, last *types.UrlStat) int {
+		cmp, cmpErr := keys.compare(first, last)
+		if cmpErr != nil && err == nil {
+			err = cmpErr
+		}
+		return cmp
+	})
 	if err != nil {
 		return nil, err
 	}
-	return merge(sortBy, firstPtr, secondPtr)
+	return &sorted, nil
 }
 
-func merge(sortBy string, first, last *types.UrlStatSlice) (*types.UrlStatSlice, error) {
-	final := new(types.UrlStatSlice)
-	i := 0
-	j := 0
-	for i < len(*first) && j < len(*last) {
-		isSorted, err := isSortedByOption(sortBy, (*first)[i], (*last)[j])
-		if err != nil {
-			return nil, err
-		}
-
-		if isSorted {
-			*final = append(*final, (*first)[i])
-			i++
-		} else {
-			*final = append(*final, (*last)[j])
-			j++
-		}
-	}
-
-	for ; i < len(*first); i++ {
-		*final = append(*final, (*first)[i])
-	}
-	for ; j < len(*last); j++ {
-		*final = append(*final, (*last)[j])
+func getLimitValue(limitValueSegment url.Values) int {
+	limitValue, err := strconv.Atoi(limitValueSegment.Get(limitFilterOption))
+	if err != nil || limitValue <= 0 {
+		limitValue = -1
 	}
-	return final, nil
-
+	return limitValue
 }
 
-func isSortedByOption(sortByOption string, first, last *types.UrlStat) (bool, error) {
-	const errInvalidSortOption = "invalid sort option selected"
-
-	// Do equals need to be considered? Any logic to solve ties
-	switch sortByOption {
-	case relevancescoreOption:
-		return isSortedByRelevanceScoreAscending(first, last)
-	case viewsOption:
-		return isSortedByViewsScoreAscending(first, last)
-	default:
-		return false, fmt.Errorf("%v %v", errInvalidSortOption, sortByOption)
+// getOffsetValue ignores invalid offsets, the same way getLimitValue ignores invalid limits
+func getOffsetValue(offsetValueSegment url.Values) int {
+	offsetValue, err := strconv.Atoi(offsetValueSegment.Get(offsetFilterOption))
+	if err != nil || offsetValue < 0 {
+		offsetValue = 0
 	}
+	return offsetValue
 }
 
-func isSortedByRelevanceScoreAscending(first, last *types.UrlStat) (bool, error) {
-	if first == nil || last == nil {
-		return false, fmt.Errorf("null pointer exception. Found when sorting Views in ascending order")
+// getStrictValue defaults to strict validation. Only an explicit "strict=false" disables it
+func getStrictValue(strictValueSegment url.Values) bool {
+	strictValue, err := strconv.ParseBool(strictValueSegment.Get(strictFilterOption))
+	if err != nil {
+		return true
 	}
-	// WARNING: Empty RelevanceScore is treated as normal 0 value
-	return first.RelevanceScore < last.RelevanceScore, nil
+	return strictValue
 }
 
-func isSortedByViewsScoreAscending(first, last *types.UrlStat) (bool, error) {
-	if first == nil || last == nil {
-		return false, fmt.Errorf("null pointer exception. Found when sorting Views in ascending order")
+// getStrictSourcesValue defaults to serving partial data. Only an explicit "strictSources=true" enables it
+func getStrictSourcesValue(strictSourcesValueSegment url.Values) bool {
+	strictSourcesValue, err := strconv.ParseBool(strictSourcesValueSegment.Get(strictSourcesOption))
+	if err != nil {
+		return false
 	}
-	// WARNING: Empty Views are treated as normal 0 value
-	return first.Views < last.Views, nil
+	return strictSourcesValue
 }
 
-func getSortOption(sortOption string) string {
-	if sortOption == "" ||
-		(sortOption != relevancescoreOption && sortOption != viewsOption) {
-		sortOption = relevancescoreOption
+// paginate returns the page starting at offset, holding at most limit records. A limit <= 0
+// returns every record after offset. The input slice is never modified.
+func paginate(u *types.UrlStatSlice, offset, limit int) (*types.UrlStatSlice, error) {
+	if u == nil {
+		return nil, fmt.Errorf("null pointer exception. Found when paginating the response")
 	}
-	return sortOption
-}
-func getLimitValue(limitValueSegment url.Values) int {
-	limitValue, err := strconv.Atoi(limitValueSegment.Get(limitFilterOption))
-	if err != nil || limitValue <= 0 {
-		limitValue = -1
+	if offset < 0 {
+		offset = 0
 	}
-	return limitValue
-}
-
-func limitReponse(u *types.UrlStatSlice, limitParams url.Values) (*types.UrlStatSlice, error) {
-	limit := getLimitValue(limitParams)
-	if u == nil {
-		return nil, fmt.Errorf("null pointer exception. Found when filtering response using Limit Option")
+	if offset > len(*u) {
+		offset = len(*u)
 	}
-	if limit <= 0 || limit > len(*u) {
-		return u, nil
+	end := len(*u)
+	// Compared against the remaining records, as offset+limit may overflow
+	if limit > 0 && limit < end-offset {
+		end = offset + limit
 	}
-	*u = (*u)[:limit]
-	return u, nil
+	page := (*u)[offset:end:end]
+	return &page, nil
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -33,8 +35,7 @@ func init() {
 	)
 
 	// Overwritting global params to reduce test time
-	retryAttempts = 1
-	backoffPeriods = []time.Duration{0}
+	defaultRetryPolicy = RetryPolicy{MaxAttempts: 1}
 }
 
 func TestNewUrlStatDataService(t *testing.T) {
@@ -124,16 +125,19 @@ func TestGetUrlStatsData_MockServer(t *testing.T) {
 			Data: []*types.UrlStat{
 				{
 					Url:            "www.example.com/abc1",
+					NormalizedUrl:  "https://www.example.com/abc1",
 					Views:          1000,
 					RelevanceScore: 0.5,
 				},
 				{
 					Url:            "www.example.com/abc2",
+					NormalizedUrl:  "https://www.example.com/abc2",
 					Views:          5000,
 					RelevanceScore: 0.1,
 				},
 				{
 					Url:            "www.example.com/abc3",
+					NormalizedUrl:  "https://www.example.com/abc3",
 					Views:          3000,
 					RelevanceScore: 0.3,
 				},
@@ -223,6 +227,10 @@ func TestGetUrlStatsData_MockServer(t *testing.T) {
 			}
 
 			result, resultErr := urlStatService.getUrlStatsData(testCtx)
+			if result != nil {
+				// Source durations are not deterministic and are covered by TestGetUrlStatsDataHttpEndpointsFromFile_Sources
+				result.Sources = nil
+			}
 			assert := reflect.DeepEqual(result, tc.expectedUrlStats)
 			if !assert {
 				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
@@ -358,16 +366,19 @@ func TestGetUrlStatsDataHttpEndpointsFromFile_MockServer(t *testing.T) {
 			Data: []*types.UrlStat{
 				{
 					Url:            "www.example.com/abc1",
+					NormalizedUrl:  "https://www.example.com/abc1",
 					Views:          1000,
 					RelevanceScore: 0.5,
 				},
 				{
 					Url:            "www.example.com/abc2",
+					NormalizedUrl:  "https://www.example.com/abc2",
 					Views:          5000,
 					RelevanceScore: 0.1,
 				},
 				{
 					Url:            "www.example.com/abc3",
+					NormalizedUrl:  "https://www.example.com/abc3",
 					Views:          3000,
 					RelevanceScore: 0.3,
 				},
@@ -462,6 +473,10 @@ func TestGetUrlStatsDataHttpEndpointsFromFile_MockServer(t *testing.T) {
 			}
 
 			result, resultErr := urlStatService.getUrlStatsDataHttpEndpointsFromFile(testCtx)
+			if result != nil {
+				// Source durations are not deterministic and are covered by TestGetUrlStatsDataHttpEndpointsFromFile_Sources
+				result.Sources = nil
+			}
 			assert := reflect.DeepEqual(result, tc.expectedUrlStats)
 			if !assert {
 				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
@@ -478,6 +493,102 @@ func TestGetUrlStatsDataHttpEndpointsFromFile_MockServer(t *testing.T) {
 	}
 }
 
+func TestGetUrlStatsDataHttpEndpointsFromFile_Sources(t *testing.T) {
+	const (
+		testFolderDataSource = "testGetUrlStatsDataHttpEndpointsFromFile_Sources"
+		autogeneratedUrlDir  = "autogenerated-url"
+		autogeneratedUrlFile = "temp-url-autocreated"
+
+		inputSuccessUrlPath = "/success.json"
+		inputFailUrlPath    = "/fail.json"
+	)
+	var (
+		testCtx context.Context = context.Background()
+
+		testInputUrlStatData = &types.UrlStatData{
+			Data: []*types.UrlStat{
+				{
+					Url:            "www.example.com/abc1",
+					NormalizedUrl:  "https://www.example.com/abc1",
+					Views:          1000,
+					RelevanceScore: 0.5,
+				},
+			},
+		}
+	)
+
+	fullPath := filepath.Join(utils.BasePath, serviceTestRelativePath, testFolderDataSource, autogeneratedUrlDir)
+	if err := os.RemoveAll(fullPath); err != nil {
+		t.Fatalf("Internal Testing error: %v", err)
+	}
+	if err := os.MkdirAll(fullPath, 0755); err != nil {
+		t.Fatalf("Internal Testing error: %v", err)
+	}
+	defer func() {
+		parentDir := fullPath[:strings.LastIndex(fullPath, "/")]
+		if err := os.RemoveAll(parentDir); err != nil {
+			t.Fatalf("Internal Testing error: %v", err)
+		}
+	}()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == inputFailUrlPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(testInputUrlStatData)
+	}))
+	defer s.Close()
+
+	fileContent := s.URL + inputFailUrlPath + "\n" + s.URL + inputSuccessUrlPath
+	fullFilePath := filepath.Join(fullPath, autogeneratedUrlFile) + fileTypeCfg
+	if err := os.WriteFile(fullFilePath, []byte(fileContent), 0644); err != nil {
+		t.Fatalf("Internal Testing error: %v", err)
+	}
+
+	urlStatService := urlStatDataService{
+		dataSourceType: urlDataSourceHttp,
+		dataSourcePath: filepath.Join(serviceTestRelativePath, testFolderDataSource, autogeneratedUrlDir),
+	}
+	result, err := urlStatService.getUrlStatsDataHttpEndpointsFromFile(testCtx)
+	if err != nil {
+		t.Fatalf("Test Failed. Expected partial data to be returned. Error: %v", err)
+	}
+
+	if !reflect.DeepEqual(result.Data, testInputUrlStatData.Data) {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v",
+			testInputUrlStatData.Data, result.Data)
+	}
+
+	expectedSources := []*types.SourceStatus{
+		{
+			Source:     s.URL + inputFailUrlPath,
+			Success:    false,
+			StatusCode: http.StatusNotFound,
+		},
+		{
+			Source:  s.URL + inputSuccessUrlPath,
+			Success: true,
+			Records: len(testInputUrlStatData.Data),
+		},
+	}
+	if len(result.Sources) != len(expectedSources) {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v",
+			len(expectedSources), len(result.Sources))
+	}
+	for i, expected := range expectedSources {
+		source := result.Sources[i]
+		if source.Source != expected.Source || source.Success != expected.Success ||
+			source.StatusCode != expected.StatusCode || source.Records != expected.Records {
+			t.Fatalf("Test Failed. Expected Result: %+v Actual Result: %+v", expected, source)
+		}
+		if source.Success != (source.Error == "") {
+			t.Fatalf("Test Failed. Expected Error to be set only for failed sources: %+v", source)
+		}
+	}
+}
+
 func TestGetUrlStatsDataHttpEndpointsFromFile_Fail(t *testing.T) {
 	var (
 		testUrlDataSourceFile = "http"
@@ -546,6 +657,10 @@ func TestGetUrlStatsDataHttpEndpointsFromFile_Fail(t *testing.T) {
 				dataSourcePath: filepath.Join(serviceTestRelativePath, tc.inputTestDir),
 			}
 			result, resultErr := urlStatService.getUrlStatsDataHttpEndpointsFromFile(testCtx)
+			if result != nil {
+				// Source durations are not deterministic and are covered by TestGetUrlStatsDataHttpEndpointsFromFile_Sources
+				result.Sources = nil
+			}
 			assert := reflect.DeepEqual(result, tc.expectedUrlStats)
 			if !assert {
 				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
@@ -623,7 +738,8 @@ func TestGetUrlStatsDataHttp_MockServer(t *testing.T) {
 				testUrl = tc.inputOverwriteUrl + inputUrlPath
 			}
 
-			result, resultErr := getUrlStatsDataHttp(testUrl)
+			urlStatService := urlStatDataService{dataSourceType: urlDataSourceHttp}
+			result, _, resultErr := urlStatService.getUrlStatsDataHttp(context.Background(), testUrl)
 			assert := reflect.DeepEqual(result, tc.expectedUrlStats)
 			if !assert {
 				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
@@ -640,6 +756,71 @@ func TestGetUrlStatsDataHttp_MockServer(t *testing.T) {
 	}
 }
 
+func TestGetUrlStatsDataHttp_Context(t *testing.T) {
+	const (
+		inputUrlPath       = "/test.json"
+		serverResponseTime = 2 * time.Second
+		maxExpectedTime    = time.Second
+	)
+
+	testCases := []struct {
+		name                string
+		inputAttemptTimeout time.Duration
+		inputCtxTimeout     time.Duration
+		inputCtxCancelled   bool
+	}{
+		{
+			name:                "attempt timeout shorter than server response time",
+			inputAttemptTimeout: 50 * time.Millisecond,
+			inputCtxTimeout:     time.Minute,
+		},
+		{
+			name:            "request context deadline shorter than server response time",
+			inputCtxTimeout: 50 * time.Millisecond,
+		},
+		{
+			name:              "request context cancelled",
+			inputCtxTimeout:   time.Minute,
+			inputCtxCancelled: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(serverResponseTime):
+				}
+			}))
+			defer s.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), tc.inputCtxTimeout)
+			defer cancel()
+			if tc.inputCtxCancelled {
+				cancel()
+			}
+
+			urlStatService := urlStatDataService{
+				dataSourceType: urlDataSourceHttp,
+				attemptTimeout: tc.inputAttemptTimeout,
+			}
+
+			start := time.Now()
+			result, _, resultErr := urlStatService.getUrlStatsDataHttp(ctx, s.URL+inputUrlPath)
+			if resultErr == nil {
+				t.Fatalf("Test Failed: %v. Expected Error to occur. Actual Result: %v", tc.name, result)
+			}
+			if elapsed := time.Since(start); elapsed > maxExpectedTime {
+				t.Fatalf("Test Failed: %v. Expected fetch to be aborted within %v. Took: %v",
+					tc.name, maxExpectedTime, elapsed)
+			}
+		})
+	}
+}
+
 func TestGetUrlStatsDataFromFile(t *testing.T) {
 	var (
 		testUrlDataSourceFile = urlDataSourceFile
@@ -669,6 +850,7 @@ func TestGetUrlStatsDataFromFile(t *testing.T) {
 				Data: []*types.UrlStat{
 					{
 						Url:            "www.example.com/abc1",
+						NormalizedUrl:  "https://www.example.com/abc1",
 						Views:          1000,
 						RelevanceScore: 0.5,
 					},
@@ -685,6 +867,7 @@ func TestGetUrlStatsDataFromFile(t *testing.T) {
 				Data: []*types.UrlStat{
 					{
 						Url:            "www.example.com/abc1",
+						NormalizedUrl:  "https://www.example.com/abc1",
 						Views:          1000,
 						RelevanceScore: 0.5,
 					},
@@ -742,6 +925,10 @@ func TestGetUrlStatsDataFromFile(t *testing.T) {
 				dataSourcePath: filepath.Join(serviceTestRelativePath, testFolderDataSource, tc.inputTestDir),
 			}
 			result, resultErr := urlStatService.getUrlStatsDataFromFile(testCtx)
+			if result != nil {
+				// Sources are covered by TestGetUrlStatsDataFromFile_Validation
+				result.Sources = nil
+			}
 			assert := reflect.DeepEqual(result, tc.expectedUrlStats)
 			if !assert {
 				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
@@ -938,7 +1125,7 @@ func TestIsValidUrlSuffixFileType(t *testing.T) {
 	}
 }
 
-func TestMergeSort(t *testing.T) {
+func TestSortByKeys_LegacyOptions(t *testing.T) {
 	urlStatA := &types.UrlStat{
 		Url:            "a",
 		Views:          1,
@@ -1069,9 +1256,15 @@ func TestMergeSort(t *testing.T) {
 
 	for _, tcOption := range testCasesOption {
 		for _, tcUrlStat := range testCasesUrlStat {
+			tcOption, tcUrlStat := tcOption, tcUrlStat
 			t.Run(tcOption.name+" - "+tcUrlStat.name, func(t *testing.T) {
 				t.Parallel()
-				result, resultErr := mergeSort(tcUrlStat.inputUrlStat, tcOption.inputOption)
+				// Unsupported options fall back to relevanceScore, as in the legacy behaviour
+				keys, err := parseSortKeys(tcOption.inputOption, false)
+				if err != nil {
+					t.Fatalf("Internal Testing error: %v", err)
+				}
+				result, resultErr := sortByKeys(tcUrlStat.inputUrlStat, keys)
 
 				assert := reflect.DeepEqual(result, tcUrlStat.expectedUrlStatResult)
 				if !assert {
@@ -1090,300 +1283,6 @@ func TestMergeSort(t *testing.T) {
 	}
 }
 
-func TestIsSortedByOption(t *testing.T) {
-	const (
-		unsupportedTag = "unsupported"
-		higherValue    = 5
-		lowerValue     = 0
-	)
-	testCases := []struct {
-		name            string
-		inputSortOption string
-		inputFirst      *types.UrlStat
-		inputLast       *types.UrlStat
-		expectedBool    bool
-		expectedErr     bool
-	}{
-		{
-			name:            "inputs: sortOption: viewsOption ; first > last",
-			inputSortOption: viewsOption,
-			inputFirst: &types.UrlStat{
-				Views: higherValue,
-			},
-			inputLast: &types.UrlStat{
-				Views: lowerValue,
-			},
-			expectedBool: false,
-		},
-		{
-			name:            "inputs: sortOption: viewsOption ; first < last",
-			inputSortOption: viewsOption,
-			inputFirst: &types.UrlStat{
-				Views: lowerValue,
-			},
-			inputLast: &types.UrlStat{
-				Views: higherValue,
-			},
-			expectedBool: true,
-		},
-		{
-			name:            "inputs: sortOption: relevancescoreOption ; first > last",
-			inputSortOption: relevancescoreOption,
-			inputFirst: &types.UrlStat{
-				RelevanceScore: higherValue,
-			},
-			inputLast: &types.UrlStat{
-				RelevanceScore: lowerValue,
-			},
-			expectedBool: false,
-		},
-		{
-			name:            "inputs: sortOption: relevancescoreOption ; first < last",
-			inputSortOption: relevancescoreOption,
-			inputFirst: &types.UrlStat{
-				RelevanceScore: lowerValue,
-			},
-			inputLast: &types.UrlStat{
-				RelevanceScore: higherValue,
-			},
-			expectedBool: true,
-		},
-		{
-			name:            "inputs: sortOption: unsupportedTag ; first: not nil; last: not nil",
-			inputSortOption: unsupportedTag,
-			inputFirst:      &types.UrlStat{},
-			inputLast:       &types.UrlStat{},
-			expectedErr:     true,
-		},
-		{
-			name:        "inputs: sortOption: empty ; first: not nil; last: empty",
-			inputLast:   &types.UrlStat{},
-			expectedErr: true,
-		},
-		{
-			name:        "inputs: sortOption: empty ; first: empty; last: nil",
-			inputFirst:  &types.UrlStat{},
-			expectedErr: true,
-		},
-		{
-			name:            "inputs: sortOption: relevancescoreOption ; first: nil; last: nil",
-			inputSortOption: relevancescoreOption,
-			expectedErr:     true,
-		},
-		{
-			name:            "inputs: sortOption: viewsOption ; first: nil; last: nil",
-			inputSortOption: relevancescoreOption,
-			expectedErr:     true,
-		},
-		{
-			name:            "inputs: sortOption: unsupportedTag ; first: nil; last: nil",
-			inputSortOption: unsupportedTag,
-			expectedErr:     true,
-		},
-		{
-			name:        "all inputs: nil",
-			expectedErr: true,
-		},
-	}
-
-	for _, tc := range testCases {
-		tc := tc
-		t.Run(tc.name, func(t *testing.T) {
-			t.Parallel()
-			result, resultErr := isSortedByOption(tc.inputSortOption, tc.inputFirst, tc.inputLast)
-			if result != tc.expectedBool {
-				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
-					tc.name, tc.expectedBool, result)
-			}
-
-			assertErr := resultErr != nil
-			if assertErr != tc.expectedErr {
-				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
-					tc.name, tc.expectedErr, resultErr.Error())
-			}
-
-		})
-	}
-}
-
-func TestIsSortedByRelevanceScoreAscending(t *testing.T) {
-	const (
-		higherValue = 5
-		lowerValue  = 0
-	)
-	testCases := []struct {
-		name         string
-		inputFirst   *types.UrlStat
-		inputLast    *types.UrlStat
-		expectedBool bool
-		expectedErr  bool
-	}{
-		{
-			name: "input: first < last",
-			inputFirst: &types.UrlStat{
-				RelevanceScore: lowerValue,
-			},
-			inputLast: &types.UrlStat{
-				RelevanceScore: higherValue,
-			},
-			expectedBool: true,
-		},
-		{
-			name: "input: first > last",
-			inputFirst: &types.UrlStat{
-				RelevanceScore: higherValue,
-			},
-			inputLast: &types.UrlStat{
-				RelevanceScore: lowerValue,
-			},
-			expectedBool: false,
-		},
-		{
-			name:        "input first: not nil; input last: nil",
-			inputLast:   &types.UrlStat{},
-			expectedErr: true,
-		},
-		{
-			name:        "input first: nil; input last: not nil",
-			inputFirst:  &types.UrlStat{},
-			expectedErr: true,
-		},
-		{
-			name:        "input: nil",
-			expectedErr: true,
-		},
-	}
-
-	for _, tc := range testCases {
-		tc := tc
-		t.Run(tc.name, func(t *testing.T) {
-			t.Parallel()
-			result, resultErr := isSortedByRelevanceScoreAscending(tc.inputFirst, tc.inputLast)
-			if result != tc.expectedBool {
-				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
-					tc.name, tc.expectedBool, result)
-			}
-
-			assertErr := resultErr != nil
-			if assertErr != tc.expectedErr {
-				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
-					tc.name, tc.expectedErr, resultErr.Error())
-			}
-
-		})
-	}
-}
-
-func TestIsSortedByViewsScoreAscending(t *testing.T) {
-	const (
-		higherValue = 5
-		lowerValue  = 0
-	)
-	testCases := []struct {
-		name         string
-		inputFirst   *types.UrlStat
-		inputLast    *types.UrlStat
-		expectedBool bool
-		expectedErr  bool
-	}{
-		{
-			name: "input: first < last",
-			inputFirst: &types.UrlStat{
-				Views: lowerValue,
-			},
-			inputLast: &types.UrlStat{
-				Views: higherValue,
-			},
-			expectedBool: true,
-		},
-		{
-			name: "input: first > last",
-			inputFirst: &types.UrlStat{
-				Views: higherValue,
-			},
-			inputLast: &types.UrlStat{
-				Views: lowerValue,
-			},
-			expectedBool: false,
-		},
-		{
-			name:        "input first: not nil; input last: nil",
-			inputLast:   &types.UrlStat{},
-			expectedErr: true,
-		},
-		{
-			name:        "input first: nil; input last: not nil",
-			inputFirst:  &types.UrlStat{},
-			expectedErr: true,
-		},
-		{
-			name:        "input: nil",
-			expectedErr: true,
-		},
-	}
-
-	for _, tc := range testCases {
-		tc := tc
-		t.Run(tc.name, func(t *testing.T) {
-			t.Parallel()
-			result, resultErr := isSortedByViewsScoreAscending(tc.inputFirst, tc.inputLast)
-			if result != tc.expectedBool {
-				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
-					tc.name, tc.expectedBool, result)
-			}
-
-			assertErr := resultErr != nil
-			if assertErr != tc.expectedErr {
-				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
-					tc.name, tc.expectedErr, resultErr.Error())
-			}
-
-		})
-	}
-}
-
-func TestGetSortOption(t *testing.T) {
-	const unsupportedTag = "unsupported"
-	testCases := []struct {
-		name     string
-		input    string
-		expected string
-	}{
-		{
-			name:     "valid input: viewsOption",
-			input:    viewsOption,
-			expected: viewsOption,
-		},
-		{
-			name:     "valid input: relevancescoreOption",
-			input:    relevancescoreOption,
-			expected: relevancescoreOption,
-		},
-		{
-			name:     "invalid input",
-			input:    unsupportedTag,
-			expected: relevancescoreOption,
-		},
-		{
-			name:     "input: empty",
-			expected: relevancescoreOption,
-		},
-	}
-
-	for _, tc := range testCases {
-		tc := tc
-		t.Run(tc.name, func(t *testing.T) {
-			t.Parallel()
-			result := getSortOption(tc.input)
-			if result != tc.expected {
-				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
-					tc.name, tc.expected, result)
-			}
-
-		})
-	}
-}
-
 func TestGetLimitValue(t *testing.T) {
 	const (
 		limitKey          = "limit"
@@ -1487,12 +1386,13 @@ func newLimitUrlValues(d int) url.Values {
 		},
 	}
 }
-func TestLimitResponse(t *testing.T) {
+func TestPaginate(t *testing.T) {
 
 	testCases := []struct {
 		name                 string
 		inputUrlStatSlice    *types.UrlStatSlice
 		inputLimit           url.Values
+		inputOffset          int
 		expectedUrlStatSlice *types.UrlStatSlice
 		expectedErr          bool
 	}{
@@ -1543,6 +1443,55 @@ func TestLimitResponse(t *testing.T) {
 				{},
 			},
 		},
+		{
+			name: "urlStat: not empty; Offset within range; Limit within range",
+			inputUrlStatSlice: &types.UrlStatSlice{
+				{Url: "a"},
+				{Url: "b"},
+				{Url: "c"},
+			},
+			inputLimit:  newLimitUrlValues(1),
+			inputOffset: 1,
+			expectedUrlStatSlice: &types.UrlStatSlice{
+				{Url: "b"},
+			},
+		},
+		{
+			name: "urlStat: not empty; Offset within range; no Limit",
+			inputUrlStatSlice: &types.UrlStatSlice{
+				{Url: "a"},
+				{Url: "b"},
+				{Url: "c"},
+			},
+			inputOffset: 1,
+			expectedUrlStatSlice: &types.UrlStatSlice{
+				{Url: "b"},
+				{Url: "c"},
+			},
+		},
+		{
+			name: "urlStat: not empty; Offset within range; largest Limit",
+			inputUrlStatSlice: &types.UrlStatSlice{
+				{Url: "a"},
+				{Url: "b"},
+				{Url: "c"},
+			},
+			inputLimit:  newLimitUrlValues(math.MaxInt),
+			inputOffset: 1,
+			expectedUrlStatSlice: &types.UrlStatSlice{
+				{Url: "b"},
+				{Url: "c"},
+			},
+		},
+		{
+			name: "urlStat: not empty; Offset > len([]urlStat)",
+			inputUrlStatSlice: &types.UrlStatSlice{
+				{Url: "a"},
+			},
+			inputLimit:           newLimitUrlValues(1),
+			inputOffset:          5,
+			expectedUrlStatSlice: &types.UrlStatSlice{},
+		},
 		{
 			name:                 "urlStat: empty; Limit > 0",
 			inputUrlStatSlice:    &types.UrlStatSlice{},
@@ -1581,12 +1530,20 @@ func TestLimitResponse(t *testing.T) {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
-			result, resultErr := limitReponse(tc.inputUrlStatSlice, tc.inputLimit)
+			var inputLen int
+			if tc.inputUrlStatSlice != nil {
+				inputLen = len(*tc.inputUrlStatSlice)
+			}
+			result, resultErr := paginate(tc.inputUrlStatSlice, tc.inputOffset, getLimitValue(tc.inputLimit))
 			assert := reflect.DeepEqual(result, tc.expectedUrlStatSlice)
 			if !assert {
 				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
 					tc.name, tc.expectedUrlStatSlice, result)
 			}
+			if tc.inputUrlStatSlice != nil && len(*tc.inputUrlStatSlice) != inputLen {
+				t.Fatalf("Test Failed: %v. The input must not be modified. Expected Length: %v Actual Length: %v",
+					tc.name, inputLen, len(*tc.inputUrlStatSlice))
+			}
 
 			assertErr := resultErr != nil
 			if assertErr != tc.expectedErr {
@@ -1597,3 +1554,137 @@ func TestLimitResponse(t *testing.T) {
 		})
 	}
 }
+
+// newRandomUrlStats returns n records with few distinct views and relevance scores, so the
+// sort keys tie often. Every dupEvery-th url is repeated to also cover ties on every key.
+func newRandomUrlStats(n, dupEvery int, seed int64) types.UrlStatSlice {
+	r := rand.New(rand.NewSource(seed))
+	urlStats := make(types.UrlStatSlice, n)
+	for i := range urlStats {
+		id := i
+		if dupEvery > 0 && i%dupEvery == 0 {
+			id = 0
+		}
+		urlStats[i] = &types.UrlStat{
+			Url:            fmt.Sprintf("www.example.com/%d", id),
+			Views:          r.Intn(100),
+			RelevanceScore: float32(r.Intn(10)) / 10,
+		}
+	}
+	return urlStats
+}
+
+func benchmarkSortForLimit(b *testing.B, sortFn func(*types.UrlStatSlice, sortKeys, int) (*types.UrlStatSlice, error)) {
+	keys, err := parseSortKeys("views:desc,relevanceScore", true)
+	if err != nil {
+		b.Fatalf("Internal Testing error: %v", err)
+	}
+	const limit = 10
+	for _, n := range []int{10000, 1000000} {
+		input := newRandomUrlStats(n, 0, 1)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			items := make(types.UrlStatSlice, n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				copy(items, input)
+				if _, err := sortFn(&items, keys, limit); err != nil {
+					b.Fatalf("Unexpected Error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSortByKeysLimit(b *testing.B) {
+	benchmarkSortForLimit(b, func(items *types.UrlStatSlice, keys sortKeys, limit int) (*types.UrlStatSlice, error) {
+		sorted, err := sortByKeys(items, keys)
+		if err != nil {
+			return nil, err
+		}
+		return paginate(sorted, 0, limit)
+	})
+}
+
+func BenchmarkTopKLimit(b *testing.B) {
+	benchmarkSortForLimit(b, topKByKeys)
+}
+
+func TestDataConfigFromEnv(t *testing.T) {
+	testCases := []struct {
+		name        string
+		inputEnv    map[string]string
+		expected    DataConfig
+		expectedErr bool
+	}{
+		{
+			name:     "no env: defaults",
+			expected: DataConfig{},
+		},
+		{
+			name: "overridden settings",
+			inputEnv: map[string]string{
+				envVarRefreshInterval:       "1m",
+				envVarFetchTimeout:          "20s",
+				envVarAttemptTimeout:        "5s",
+				envVarMaxConcurrency:        "8",
+				envVarMaxConcurrencyPerHost: "2",
+				envVarMaxSnapshotAge:        "10m",
+			},
+			expected: DataConfig{
+				RefreshInterval:       time.Minute,
+				FetchTimeout:          20 * time.Second,
+				AttemptTimeout:        5 * time.Second,
+				MaxConcurrency:        8,
+				MaxConcurrencyPerHost: 2,
+				MaxSnapshotAge:        10 * time.Minute,
+			},
+		},
+		{
+			name: "invalid duration",
+			inputEnv: map[string]string{
+				envVarRefreshInterval: "30",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "negative duration",
+			inputEnv: map[string]string{
+				envVarMaxSnapshotAge: "-1m",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid concurrency",
+			inputEnv: map[string]string{
+				envVarMaxConcurrency: "many",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "negative concurrency",
+			inputEnv: map[string]string{
+				envVarMaxConcurrencyPerHost: "-1",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result, resultErr := DataConfigFromEnv(func(key string) string {
+				return tc.inputEnv[key]
+			})
+			assertErr := resultErr != nil
+			if assertErr != tc.expectedErr {
+				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
+					tc.name, tc.expectedErr, resultErr)
+			}
+			if !tc.expectedErr && !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %+v Actual Result: %+v",
+					tc.name, tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+const (
+	sortKeySeparator       = ","
+	sortDirectionSeparator = ":"
+
+	sortDirectionAsc  = "asc"
+	sortDirectionDesc = "desc"
+)
+
+var validSortOptions = []string{relevancescoreOption, viewsOption, urlOption}
+
+type invalidSortKeyError struct {
+	segment string
+}
+
+func (e *invalidSortKeyError) Error() string {
+	return fmt.Sprintf("invalid sort key %q. Valid keys: %s, optionally suffixed with %q or %q",
+		e.segment, strings.Join(validSortOptions, ", "),
+		sortDirectionSeparator+sortDirectionAsc, sortDirectionSeparator+sortDirectionDesc)
+}
+
+type sortKey struct {
+	option     string
+	descending bool
+}
+
+// sortKeys is a comparator chain. Keys are compared in order and the next key
+// is only consulted when the previous one considers both items equal.
+type sortKeys []sortKey
+
+// parseSortKeys parses specs such as "views:desc,relevanceScore:asc,url".
+// The direction defaults to ascending and a trailing "url" key is always
+// added to break ties, so the resulting order is deterministic.
+// When strict is false, unknown keys fall back to relevanceScore and unknown
+// directions fall back to ascending, matching the legacy behaviour.
+func parseSortKeys(sortBy string, strict bool) (sortKeys, error) {
+	var keys sortKeys
+	for _, segment := range strings.Split(sortBy, sortKeySeparator) {
+		option, direction, _ := strings.Cut(segment, sortDirectionSeparator)
+		if strict && (!isValidSortOption(option) || !isValidSortDirection(direction)) {
+			return nil, &invalidRequestError{
+				code: errCodeInvalidSortKey,
+				err:  &invalidSortKeyError{segment: segment},
+			}
+		}
+		if !isValidSortOption(option) {
+			option = relevancescoreOption
+		}
+		keys = append(keys, sortKey{
+			option:     option,
+			descending: direction == sortDirectionDesc,
+		})
+	}
+	return keys.withTieBreaker(), nil
+}
+
+func isValidSortOption(option string) bool {
+	for _, validOption := range validSortOptions {
+		if option == validOption {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidSortDirection(direction string) bool {
+	return direction == "" || direction == sortDirectionAsc || direction == sortDirectionDesc
+}
+
+func (keys sortKeys) withTieBreaker() sortKeys {
+	for _, key := range keys {
+		if key.option == urlOption {
+			return keys
+		}
+	}
+	return append(keys, sortKey{option: urlOption})
+}
+
+func (keys sortKeys) compare(first, last *types.UrlStat) (int, error) {
+	if first == nil || last == nil {
+		return 0, fmt.Errorf("null pointer exception. Found when sorting Url Data")
+	}
+	for _, key := range keys {
+		c, err := compareByOption(key.option, first, last)
+		if err != nil {
+			return 0, err
+		}
+		if key.descending {
+			c = -c
+		}
+		if c != 0 {
+			return c, nil
+		}
+	}
+	return 0, nil
+}
+
+// compareByOption compares in ascending order. Empty Views and RelevanceScore are treated as 0.
+func compareByOption(sortByOption string, first, last *types.UrlStat) (int, error) {
+	switch sortByOption {
+	case relevancescoreOption:
+		return cmp.Compare(first.RelevanceScore, last.RelevanceScore), nil
+	case viewsOption:
+		return cmp.Compare(first.Views, last.Views), nil
+	case urlOption:
+		return cmp.Compare(first.Url, last.Url), nil
+	default:
+		return 0, fmt.Errorf("invalid sort option selected %v", sortByOption)
+	}
+}
@@ -0,0 +1,319 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+func TestParseSortKeys(t *testing.T) {
+	const unsupportedTag = "unsupported"
+
+	testCases := []struct {
+		name        string
+		input       string
+		inputStrict bool
+		expected    sortKeys
+		expectedErr bool
+	}{
+		{
+			name:  "single key, no direction",
+			input: viewsOption,
+			expected: sortKeys{
+				{option: viewsOption},
+				{option: urlOption},
+			},
+		},
+		{
+			name:  "single key, descending",
+			input: viewsOption + ":desc",
+			expected: sortKeys{
+				{option: viewsOption, descending: true},
+				{option: urlOption},
+			},
+		},
+		{
+			name:  "multiple keys with explicit url tie-breaker",
+			input: "views:desc,relevanceScore:asc,url:desc",
+			expected: sortKeys{
+				{option: viewsOption, descending: true},
+				{option: relevancescoreOption},
+				{option: urlOption, descending: true},
+			},
+		},
+		{
+			name:        "strict: valid keys",
+			input:       "views:desc,url",
+			inputStrict: true,
+			expected: sortKeys{
+				{option: viewsOption, descending: true},
+				{option: urlOption},
+			},
+		},
+		{
+			name:        "strict: unsupported key",
+			input:       unsupportedTag,
+			inputStrict: true,
+			expectedErr: true,
+		},
+		{
+			name:        "strict: unsupported direction",
+			input:       viewsOption + ":" + unsupportedTag,
+			inputStrict: true,
+			expectedErr: true,
+		},
+		{
+			name:        "strict: empty key in list",
+			input:       viewsOption + ",",
+			inputStrict: true,
+			expectedErr: true,
+		},
+		{
+			name:  "legacy: unsupported key defaults to relevanceScore",
+			input: unsupportedTag,
+			expected: sortKeys{
+				{option: relevancescoreOption},
+				{option: urlOption},
+			},
+		},
+		{
+			name:  "legacy: unsupported direction defaults to ascending",
+			input: viewsOption + ":" + unsupportedTag,
+			expected: sortKeys{
+				{option: viewsOption},
+				{option: urlOption},
+			},
+		},
+		{
+			name:  "legacy: empty input defaults to relevanceScore",
+			input: "",
+			expected: sortKeys{
+				{option: relevancescoreOption},
+				{option: urlOption},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result, resultErr := parseSortKeys(tc.input, tc.inputStrict)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expected, result)
+			}
+			assertErr := resultErr != nil
+			if assertErr != tc.expectedErr {
+				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
+					tc.name, tc.expectedErr, resultErr)
+			}
+		})
+	}
+}
+
+func TestSortByKeys(t *testing.T) {
+	urlStatA := &types.UrlStat{Url: "a", Views: 2, RelevanceScore: 0.1}
+	urlStatB := &types.UrlStat{Url: "b", Views: 2, RelevanceScore: 0.2}
+	urlStatC := &types.UrlStat{Url: "c", Views: 1, RelevanceScore: 0.2}
+	urlStatD := &types.UrlStat{Url: "d", Views: 3, RelevanceScore: 0.1}
+
+	input := types.UrlStatSlice{urlStatC, urlStatB, urlStatD, urlStatA}
+
+	testCases := []struct {
+		name     string
+		inputKey string
+		expected *types.UrlStatSlice
+	}{
+		{
+			name:     "views descending, ties broken by url",
+			inputKey: "views:desc",
+			expected: &types.UrlStatSlice{urlStatD, urlStatA, urlStatB, urlStatC},
+		},
+		{
+			name:     "views descending, relevanceScore descending",
+			inputKey: "views:desc,relevanceScore:desc",
+			expected: &types.UrlStatSlice{urlStatD, urlStatB, urlStatA, urlStatC},
+		},
+		{
+			name:     "relevanceScore ascending, views descending",
+			inputKey: "relevanceScore,views:desc",
+			expected: &types.UrlStatSlice{urlStatD, urlStatA, urlStatB, urlStatC},
+		},
+		{
+			name:     "url descending",
+			inputKey: "url:desc",
+			expected: &types.UrlStatSlice{urlStatD, urlStatC, urlStatB, urlStatA},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			items := append(types.UrlStatSlice{}, input...)
+			keys, err := parseSortKeys(tc.inputKey, true)
+			if err != nil {
+				t.Fatalf("Test Failed: %v. Unexpected Error: %v", tc.name, err)
+			}
+			result, err := sortByKeys(&items, keys)
+			if err != nil {
+				t.Fatalf("Test Failed: %v. Unexpected Error: %v", tc.name, err)
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestSortKeysCompare(t *testing.T) {
+	first := &types.UrlStat{Url: "a", Views: 1}
+	last := &types.UrlStat{Url: "b", Views: 1}
+
+	testCases := []struct {
+		name        string
+		inputKeys   sortKeys
+		inputFirst  *types.UrlStat
+		inputLast   *types.UrlStat
+		expected    int
+		expectedErr bool
+	}{
+		{
+			name:       "equal on views, no tie-breaker",
+			inputKeys:  sortKeys{{option: viewsOption}},
+			inputFirst: first,
+			inputLast:  last,
+			expected:   0,
+		},
+		{
+			name:       "equal on views, url tie-breaker",
+			inputKeys:  sortKeys{{option: viewsOption}, {option: urlOption}},
+			inputFirst: first,
+			inputLast:  last,
+			expected:   -1,
+		},
+		{
+			name:       "equal on views, url descending tie-breaker",
+			inputKeys:  sortKeys{{option: viewsOption}, {option: urlOption, descending: true}},
+			inputFirst: first,
+			inputLast:  last,
+			expected:   1,
+		},
+		{
+			name:        "nil input",
+			inputKeys:   sortKeys{{option: viewsOption}},
+			inputFirst:  first,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result, resultErr := tc.inputKeys.compare(tc.inputFirst, tc.inputLast)
+			if result != tc.expected {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expected, result)
+			}
+			assertErr := resultErr != nil
+			if assertErr != tc.expectedErr {
+				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
+					tc.name, tc.expectedErr, resultErr)
+			}
+		})
+	}
+}
+
+func TestCompareByOption(t *testing.T) {
+	const (
+		unsupportedTag = "unsupported"
+		higherValue    = 5
+		lowerValue     = 0
+	)
+	testCases := []struct {
+		name            string
+		inputSortOption string
+		inputFirst      *types.UrlStat
+		inputLast       *types.UrlStat
+		expected        int
+		expectedErr     bool
+	}{
+		{
+			name:            "viewsOption: first > last",
+			inputSortOption: viewsOption,
+			inputFirst:      &types.UrlStat{Views: higherValue},
+			inputLast:       &types.UrlStat{Views: lowerValue},
+			expected:        1,
+		},
+		{
+			name:            "viewsOption: first < last",
+			inputSortOption: viewsOption,
+			inputFirst:      &types.UrlStat{Views: lowerValue},
+			inputLast:       &types.UrlStat{Views: higherValue},
+			expected:        -1,
+		},
+		{
+			name:            "viewsOption: empty views treated as 0",
+			inputSortOption: viewsOption,
+			inputFirst:      &types.UrlStat{},
+			inputLast:       &types.UrlStat{Views: lowerValue},
+			expected:        0,
+		},
+		{
+			name:            "relevancescoreOption: first > last",
+			inputSortOption: relevancescoreOption,
+			inputFirst:      &types.UrlStat{RelevanceScore: higherValue},
+			inputLast:       &types.UrlStat{RelevanceScore: lowerValue},
+			expected:        1,
+		},
+		{
+			name:            "relevancescoreOption: first < last",
+			inputSortOption: relevancescoreOption,
+			inputFirst:      &types.UrlStat{RelevanceScore: lowerValue},
+			inputLast:       &types.UrlStat{RelevanceScore: higherValue},
+			expected:        -1,
+		},
+		{
+			name:            "urlOption: first < last",
+			inputSortOption: urlOption,
+			inputFirst:      &types.UrlStat{Url: "a"},
+			inputLast:       &types.UrlStat{Url: "b"},
+			expected:        -1,
+		},
+		{
+			name:            "urlOption: equal",
+			inputSortOption: urlOption,
+			inputFirst:      &types.UrlStat{Url: "a"},
+			inputLast:       &types.UrlStat{Url: "a"},
+			expected:        0,
+		},
+		{
+			name:            "unsupported option",
+			inputSortOption: unsupportedTag,
+			inputFirst:      &types.UrlStat{},
+			inputLast:       &types.UrlStat{},
+			expectedErr:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result, resultErr := compareByOption(tc.inputSortOption, tc.inputFirst, tc.inputLast)
+			if result != tc.expected {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expected, result)
+			}
+			assertErr := resultErr != nil
+			if assertErr != tc.expectedErr {
+				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
+					tc.name, tc.expectedErr, resultErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// streamFlushRecords is the number of records written between two flushes to the client
+const streamFlushRecords = 1000
+
+type writeTimeoutContextKey struct{}
+
+// contextWithWriteTimeout lets streamed responses extend the write deadline of the server
+func contextWithWriteTimeout(ctx context.Context, writeTimeout time.Duration) context.Context {
+	return context.WithValue(ctx, writeTimeoutContextKey{}, writeTimeout)
+}
+
+func writeTimeoutFromContext(ctx context.Context) time.Duration {
+	writeTimeout, _ := ctx.Value(writeTimeoutContextKey{}).(time.Duration)
+	return writeTimeout
+}
+
+// streamWriter buffers the encoded records and flushes them to the client every flushRecords
+// records, so the encoded response is never held in memory. It stops with the context error
+// once the client disconnected. Each flush extends the write deadline by the write timeout of
+// the server, so long responses are not cut off as long as every chunk is written in time.
+type streamWriter struct {
+	ctx          context.Context
+	buf          *bufio.Writer
+	flusher      func() error
+	flushRecords int
+	records      int
+}
+
+// newStreamWriter flushes through w when it is an http.ResponseWriter supporting it
+func newStreamWriter(ctx context.Context, w io.Writer) *streamWriter {
+	writeTimeout := writeTimeoutFromContext(ctx)
+	sw := &streamWriter{
+		ctx:          ctx,
+		buf:          bufio.NewWriter(w),
+		flusher:      func() error { return nil },
+		flushRecords: streamFlushRecords,
+	}
+	if rw, ok := w.(http.ResponseWriter); ok {
+		rc := http.NewResponseController(rw)
+		sw.flusher = func() error {
+			if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+			if writeTimeout <= 0 {
+				return nil
+			}
+			if err := rc.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+			return nil
+		}
+	}
+	return sw
+}
+
+func (sw *streamWriter) Write(p []byte) (int, error) {
+	return sw.buf.Write(p)
+}
+
+func (sw *streamWriter) WriteString(s string) (int, error) {
+	return sw.buf.WriteString(s)
+}
+
+// recordWritten flushes every flushRecords records
+func (sw *streamWriter) recordWritten() error {
+	sw.records++
+	if sw.records%sw.flushRecords != 0 {
+		return nil
+	}
+	return sw.flush()
+}
+
+func (sw *streamWriter) flush() error {
+	if err := sw.ctx.Err(); err != nil {
+		return err
+	}
+	if err := sw.buf.Flush(); err != nil {
+		return err
+	}
+	return sw.flusher()
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+// flushRecorder counts the flushes and calls onFlush after each of them. It also records the
+// write deadlines set through http.ResponseController.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushes   int
+	onFlush   func()
+	deadlines []time.Time
+}
+
+func (r *flushRecorder) SetWriteDeadline(deadline time.Time) error {
+	r.deadlines = append(r.deadlines, deadline)
+	return nil
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushes++
+	r.ResponseRecorder.Flush()
+	if r.onFlush != nil {
+		r.onFlush()
+	}
+}
+
+func newStreamTestResponse(n int) *types.ResponseUrlStats {
+	data := newUrlStatTestData(n)
+	return &types.ResponseUrlStats{SortedUrlStats: &data.Data, Count: n, Total: n}
+}
+
+func TestStreamWriter_Flush(t *testing.T) {
+	testCases := []struct {
+		name            string
+		inputRecords    int
+		expectedFlushes int
+	}{
+		{
+			name:            "no records: flushed once",
+			inputRecords:    0,
+			expectedFlushes: 1,
+		},
+		{
+			name:            "below the flush interval: flushed once",
+			inputRecords:    streamFlushRecords - 1,
+			expectedFlushes: 1,
+		},
+		{
+			name:            "flushed every interval and at the end",
+			inputRecords:    2*streamFlushRecords + 500,
+			expectedFlushes: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		for _, encoder := range urlStatsEncoders {
+			encoder := encoder
+			t.Run(tc.name+": "+encoder.format, func(t *testing.T) {
+				t.Parallel()
+				rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+				if err := encoder.encode(newStreamWriter(context.Background(), rec), newStreamTestResponse(tc.inputRecords)); err != nil {
+					t.Fatalf("Test Failed: %v. Returned Error: %v", tc.name, err)
+				}
+				if rec.flushes != tc.expectedFlushes {
+					t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v", tc.name, tc.expectedFlushes, rec.flushes)
+				}
+			})
+		}
+	}
+}
+
+func TestStreamWriter_ClientDisconnect(t *testing.T) {
+	const inputRecords = 5 * streamFlushRecords
+
+	for _, encoder := range urlStatsEncoders {
+		encoder := encoder
+		t.Run(encoder.format, func(t *testing.T) {
+			t.Parallel()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			// The client disconnects after receiving the first chunk
+			rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder(), onFlush: cancel}
+
+			err := encoder.encode(newStreamWriter(ctx, rec), newStreamTestResponse(inputRecords))
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v", encoder.format, context.Canceled, err)
+			}
+			if rec.flushes != 1 {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v", encoder.format, 1, rec.flushes)
+			}
+			if written := strings.Count(rec.Body.String(), "www.example.com/"); written >= 2*streamFlushRecords {
+				t.Fatalf("Test Failed: %v. Expected fewer than %v records to be written. Actual Result: %v",
+					encoder.format, 2*streamFlushRecords, written)
+			}
+		})
+	}
+}
+
+func TestHandleSortKey_streamFlush(t *testing.T) {
+	apiServer := NewApiServer(&stubService{data: newUrlStatTestData(2*streamFlushRecords + 500)})
+
+	rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	apiServer.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/sortkey/views?format=ndjson", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", http.StatusOK, rec.Code)
+	}
+	if rec.flushes != 3 {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", 3, rec.flushes)
+	}
+}
+
+func TestHandleSortKey_streamWriteDeadline(t *testing.T) {
+	const inputWriteTimeout = time.Minute
+
+	testCases := []struct {
+		name                string
+		inputAcceptEncoding string
+		inputWriteTimeout   time.Duration
+		expectedDeadlines   int
+	}{
+		{
+			name:              "extended on every flush",
+			inputWriteTimeout: inputWriteTimeout,
+			expectedDeadlines: 3,
+		},
+		{
+			name:                "extended on every flush: compressed",
+			inputAcceptEncoding: "gzip",
+			inputWriteTimeout:   inputWriteTimeout,
+			expectedDeadlines:   3,
+		},
+		{
+			name:              "no write timeout",
+			expectedDeadlines: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			apiServer := NewApiServer(&stubService{data: newUrlStatTestData(2*streamFlushRecords + 500)})
+			req := httptest.NewRequest(http.MethodGet, "/v1/sortkey/views?format=ndjson", nil)
+			if tc.inputWriteTimeout > 0 {
+				req = req.WithContext(contextWithWriteTimeout(req.Context(), tc.inputWriteTimeout))
+			}
+			if tc.inputAcceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.inputAcceptEncoding)
+			}
+			rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+			start := time.Now()
+			apiServer.ServeHTTP(rec, req)
+
+			if len(rec.deadlines) != tc.expectedDeadlines {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v", tc.name, tc.expectedDeadlines, len(rec.deadlines))
+			}
+			for _, deadline := range rec.deadlines {
+				if deadline.Before(start.Add(tc.inputWriteTimeout)) {
+					t.Fatalf("Test Failed: %v. Expected a deadline after: %v Actual Result: %v",
+						tc.name, start.Add(tc.inputWriteTimeout), deadline)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamWriter_longerThanWriteTimeout(t *testing.T) {
+	const (
+		inputWriteTimeout = 300 * time.Millisecond
+		inputChunks       = 6
+		inputChunkDelay   = 100 * time.Millisecond
+	)
+
+	// Every chunk is written in time, while the whole response takes twice the write timeout
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		sw := newStreamWriter(r.Context(), w)
+		for i := 0; i < inputChunks; i++ {
+			time.Sleep(inputChunkDelay)
+			if _, err := fmt.Fprintf(sw, "chunk %d\n", i); err != nil {
+				return
+			}
+			if err := sw.flush(); err != nil {
+				return
+			}
+		}
+	})
+	apiServer := NewApiServer(&stubService{}, WithServerConfig(ServerConfig{WriteTimeout: inputWriteTimeout}))
+	srv := apiServer.newHttpServer("127.0.0.1:0", mux)
+	if _, err := serve(srv); err != nil {
+		t.Fatalf("Internal Testing error: %v", err)
+	}
+	defer srv.Close()
+
+	resp, err := http.Get("http://" + srv.Addr + "/stream")
+	if err != nil {
+		t.Fatalf("Internal Testing error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Test Failed. Expected the response not to be cut off. Error: %v", err)
+	}
+	if chunks := strings.Count(string(body), "chunk"); chunks != inputChunks {
+		t.Fatalf("Test Failed. Expected Result: %v Actual Result: %v", inputChunks, chunks)
+	}
+}
+
+func BenchmarkStreamEncoders(b *testing.B) {
+	for _, size := range []int{10000, 1000000} {
+		resp := newStreamTestResponse(size)
+		for _, encoder := range urlStatsEncoders {
+			b.Run(fmt.Sprintf("%s/%d", encoder.format, size), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if err := encoder.encode(newStreamWriter(context.Background(), io.Discard), resp); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"container/heap"
+	"fmt"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+// topKThreshold selects top-K instead of a full sort when the requested
+// page ends within the first 1/topKThreshold of the records
+const topKThreshold = 4
+
+// sortForPage sorts only as much as the page needs. The records past the page may be left out.
+func sortForPage(items *types.UrlStatSlice, keys sortKeys, page pageRequest) (*types.UrlStatSlice, error) {
+	if items == nil {
+		return nil, fmt.Errorf("null pointer exception. Found when sorting Url Data")
+	}
+	// Written to not overflow, as offset and limit come from the request
+	if page.limit > 0 && page.limit < len(*items)/topKThreshold-page.offset {
+		return topKByKeys(items, keys, page.offset+page.limit)
+	}
+	return sortByKeys(items, keys)
+}
+
+// topKByKeys returns the first k records of the sorted order in O(n log k). Records comparing
+// equal on every key keep their input order, so the result matches sortByKeys.
+func topKByKeys(items *types.UrlStatSlice, keys sortKeys, k int) (*types.UrlStatSlice, error) {
+	if items == nil {
+		return nil, fmt.Errorf("null pointer exception. Found when sorting Url Data")
+	}
+	if k > len(*items) {
+		k = len(*items)
+	}
+	if k < 0 {
+		k = 0
+	}
+
+	h := &topKHeap{keys: keys, entries: make([]topKEntry, 0, k)}
+	for i, urlStat := range *items {
+		entry := topKEntry{urlStat: urlStat, index: i}
+		if len(h.entries) < k {
+			heap.Push(h, entry)
+		} else if k > 0 && h.before(entry, h.entries[0]) {
+			h.entries[0] = entry
+			heap.Fix(h, 0)
+		}
+		if h.err != nil {
+			return nil, h.err
+		}
+	}
+
+	sorted := make(types.UrlStatSlice, len(h.entries))
+	for i := len(sorted) - 1; i >= 0; i-- {
+		sorted[i] = heap.Pop(h).(topKEntry).urlStat
+	}
+	return &sorted, h.err
+}
+
+type topKEntry struct {
+	urlStat *types.UrlStat
+	index   int
+}
+
+// topKHeap keeps the entry sorting last at its root, so it is the first one to be replaced.
+// Comparison errors can not be returned through heap.Interface and are kept in err.
+type topKHeap struct {
+	keys    sortKeys
+	entries []topKEntry
+	err     error
+}
+
+// before reports whether a sorts before b, falling back to the input order on ties
+func (h *topKHeap) before(a, b topKEntry) bool {
+	cmp, err := h.keys.compare(a.urlStat, b.urlStat)
+	if err != nil {
+		if h.err == nil {
+			h.err = err
+		}
+		return false
+	}
+	if cmp != 0 {
+		return cmp < 0
+	}
+	return a.index < b.index
+}
+
+func (h *topKHeap) Len() int           { return len(h.entries) }
+func (h *topKHeap) Less(i, j int) bool { return h.before(h.entries[j], h.entries[i]) }
+func (h *topKHeap) Swap(i, j int)      { h.entries[i], h.entries[j] = h.entries[j], h.entries[i] }
+
+func (h *topKHeap) Push(x any) {
+	h.entries = append(h.entries, x.(topKEntry))
+}
+
+func (h *topKHeap) Pop() any {
+	last := h.entries[len(h.entries)-1]
+	h.entries = h.entries[:len(h.entries)-1]
+	return last
+}
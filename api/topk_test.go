@@ -0,0 +1,99 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+func TestTopKByKeys(t *testing.T) {
+	input := newRandomUrlStats(500, 7, 42)
+
+	testCases := []struct {
+		name     string
+		inputKey string
+		inputK   int
+	}{
+		{
+			name:     "views descending: top 10",
+			inputKey: "views:desc",
+			inputK:   10,
+		},
+		{
+			name:     "relevanceScore ascending, views descending: top 25",
+			inputKey: "relevanceScore,views:desc",
+			inputK:   25,
+		},
+		{
+			name:     "url ascending: duplicated urls keep their input order",
+			inputKey: "url",
+			inputK:   80,
+		},
+		{
+			name:     "views ascending: top 1",
+			inputKey: "views",
+			inputK:   1,
+		},
+		{
+			name:     "k larger than input",
+			inputKey: "views:desc",
+			inputK:   1000,
+		},
+		{
+			name:     "k zero",
+			inputKey: "views:desc",
+			inputK:   0,
+		},
+		{
+			name:     "k negative",
+			inputKey: "views:desc",
+			inputK:   -1,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			keys, err := parseSortKeys(tc.inputKey, true)
+			if err != nil {
+				t.Fatalf("Test Failed: %v. Unexpected Error: %v", tc.name, err)
+			}
+			items := append(types.UrlStatSlice{}, input...)
+			sorted, err := sortByKeys(&items, keys)
+			if err != nil {
+				t.Fatalf("Test Failed: %v. Unexpected Error: %v", tc.name, err)
+			}
+			expected, _ := paginate(sorted, 0, tc.inputK)
+			if tc.inputK <= 0 {
+				expected = &types.UrlStatSlice{}
+			}
+
+			items = append(types.UrlStatSlice{}, input...)
+			result, err := topKByKeys(&items, keys, tc.inputK)
+			if err != nil {
+				t.Fatalf("Test Failed: %v. Unexpected Error: %v", tc.name, err)
+			}
+			if !reflect.DeepEqual(result, expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, expected, result)
+			}
+			if !reflect.DeepEqual(items, input) {
+				t.Fatalf("Test Failed: %v. The input must not be modified", tc.name)
+			}
+		})
+	}
+}
+
+func TestTopKByKeys_Nil(t *testing.T) {
+	keys, _ := parseSortKeys("views", true)
+
+	if _, err := topKByKeys(nil, keys, 1); err == nil {
+		t.Fatalf("Test Failed: nil slice. Expected Error to occur: true. Returned Error: %v", err)
+	}
+	items := types.UrlStatSlice{{Url: "a"}, nil, {Url: "b"}}
+	if _, err := topKByKeys(&items, keys, 1); err == nil {
+		t.Fatalf("Test Failed: nil record. Expected Error to occur: true. Returned Error: %v", err)
+	}
+}
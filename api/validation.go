@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+const (
+	envVarValidationPolicy                = "VALIDATION_POLICY"
+	envVarValidationSourcePolicies        = "VALIDATION_SOURCE_POLICIES"
+	envVarValidationDisallowUnknownFields = "VALIDATION_DISALLOW_UNKNOWN_FIELDS"
+
+	maxReportedViolations = 20
+)
+
+// ValidationPolicy controls what happens to a Data Source payload containing invalid records
+type ValidationPolicy string
+
+const (
+	// ValidationReject fails the whole Data Source
+	ValidationReject ValidationPolicy = "reject"
+	// ValidationSkip drops the invalid records
+	ValidationSkip ValidationPolicy = "skip"
+	// ValidationWarn keeps the invalid records
+	ValidationWarn ValidationPolicy = "warn"
+)
+
+var defaultValidationPolicy = ValidationSkip
+
+// ValidationConfig holds the default policy and per-source overrides, keyed by the
+// Data Source Endpoint url or, for file-based sources, by the file name.
+// Payloads that can not be decoded always fail their Data Source, regardless of the policy.
+type ValidationConfig struct {
+	Policy                ValidationPolicy
+	SourcePolicies        map[string]ValidationPolicy
+	DisallowUnknownFields bool
+}
+
+func (c ValidationConfig) policyFor(source string) ValidationPolicy {
+	if policy, ok := c.SourcePolicies[source]; ok {
+		return policy
+	}
+	if c.Policy == "" {
+		return defaultValidationPolicy
+	}
+	return c.Policy
+}
+
+type validationReport struct {
+	violations []*types.Violation
+	skipped    int
+}
+
+// apply adds the report to the status of its source. It is a no-op on a nil report
+func (r *validationReport) apply(status *types.SourceStatus) {
+	if r == nil {
+		return
+	}
+	status.Skipped = r.skipped
+	status.ViolationCount = len(r.violations)
+	status.Violations = r.violations
+	if len(status.Violations) > maxReportedViolations {
+		status.Violations = status.Violations[:maxReportedViolations]
+	}
+}
+
+func decodeUrlStatData(r io.Reader, disallowUnknownFields bool) (*types.UrlStatData, error) {
+	dec := json.NewDecoder(r)
+	if disallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	urlStats := new(types.UrlStatData)
+	if err := dec.Decode(urlStats); err != nil {
+		return nil, &invalidPayloadError{err: err}
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, &invalidPayloadError{err: errors.New("unexpected data after the JSON document")}
+	}
+	return urlStats, nil
+}
+
+// validate applies the policy configured for the source. With ValidationReject, the report
+// is returned alongside the error so the violations can still be reported.
+func (c ValidationConfig) validate(source string, urlStats *types.UrlStatData) (*types.UrlStatData, *validationReport, error) {
+	policy := c.policyFor(source)
+	report := new(validationReport)
+	valid := new(types.UrlStatData)
+	invalidRecords := 0
+
+	for i, urlStat := range urlStats.Data {
+		violations := validateUrlStat(i, urlStat)
+		if len(violations) == 0 {
+			valid.Data = append(valid.Data, urlStat)
+			continue
+		}
+		invalidRecords++
+		report.violations = append(report.violations, violations...)
+		if policy == ValidationWarn {
+			valid.Data = append(valid.Data, urlStat)
+		}
+	}
+
+	if invalidRecords == 0 {
+		return urlStats, report, nil
+	}
+	slog.Warn("Invalid records found in Data Source", "source", source, "invalidRecords", invalidRecords, "policy", policy)
+	switch policy {
+	case ValidationReject:
+		return nil, report, &invalidPayloadError{err: fmt.Errorf("%d records failed validation", invalidRecords)}
+	case ValidationSkip:
+		report.skipped = invalidRecords
+	}
+	return valid, report, nil
+}
+
+// validateUrlStat returns one violation per invalid field
+func validateUrlStat(index int, urlStat *types.UrlStat) []*types.Violation {
+	if urlStat == nil {
+		return []*types.Violation{{Index: index, Message: "record is null"}}
+	}
+	var violations []*types.Violation
+	if strings.TrimSpace(urlStat.Url) == "" {
+		violations = append(violations, &types.Violation{Index: index, Field: urlOption, Message: "is required"})
+	}
+	if urlStat.Views < 0 {
+		violations = append(violations, &types.Violation{Index: index, Url: urlStat.Url, Field: viewsOption,
+			Message: fmt.Sprintf("must not be negative. Got: %d", urlStat.Views)})
+	}
+	if urlStat.RelevanceScore < 0 || urlStat.RelevanceScore > 1 {
+		violations = append(violations, &types.Violation{Index: index, Url: urlStat.Url, Field: relevancescoreOption,
+			Message: fmt.Sprintf("must be between 0 and 1. Got: %v", urlStat.RelevanceScore)})
+	}
+	return violations
+}
+
+// ValidationConfigFromEnv reads VALIDATION_POLICY, VALIDATION_SOURCE_POLICIES, formatted as
+// comma separated source=policy pairs, and VALIDATION_DISALLOW_UNKNOWN_FIELDS.
+func ValidationConfigFromEnv(getenv func(string) string) (ValidationConfig, error) {
+	config := ValidationConfig{Policy: defaultValidationPolicy}
+
+	if value := getenv(envVarValidationPolicy); value != "" {
+		config.Policy = ValidationPolicy(value)
+	}
+	if value := getenv(envVarValidationSourcePolicies); value != "" {
+		config.SourcePolicies = make(map[string]ValidationPolicy)
+		for _, pair := range strings.Split(value, ",") {
+			i := strings.LastIndex(pair, "=")
+			if i <= 0 {
+				return config, fmt.Errorf("invalid %s: expected source=policy. Got: %q", envVarValidationSourcePolicies, pair)
+			}
+			config.SourcePolicies[strings.TrimSpace(pair[:i])] = ValidationPolicy(strings.TrimSpace(pair[i+1:]))
+		}
+	}
+	if value := getenv(envVarValidationDisallowUnknownFields); value != "" {
+		var err error
+		if config.DisallowUnknownFields, err = strconv.ParseBool(value); err != nil {
+			return config, fmt.Errorf("invalid %s: %w", envVarValidationDisallowUnknownFields, err)
+		}
+	}
+	return config, config.validatePolicies()
+}
+
+func (c ValidationConfig) validatePolicies() error {
+	if !isValidValidationPolicy(c.Policy) {
+		return fmt.Errorf("validation: unsupported policy %q", c.Policy)
+	}
+	for source, policy := range c.SourcePolicies {
+		if !isValidValidationPolicy(policy) {
+			return fmt.Errorf("validation: unsupported policy %q for source %v", policy, source)
+		}
+	}
+	return nil
+}
+
+func isValidValidationPolicy(policy ValidationPolicy) bool {
+	return policy == ValidationReject || policy == ValidationSkip || policy == ValidationWarn
+}
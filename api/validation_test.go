@@ -0,0 +1,319 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/felipe88alves/sortKeyHttpServer/types"
+)
+
+func TestDecodeUrlStatData(t *testing.T) {
+	testCases := []struct {
+		name                       string
+		input                      string
+		inputDisallowUnknownFields bool
+		expected                   *types.UrlStatData
+		expectedErr                bool
+	}{
+		{
+			name:  "valid payload",
+			input: `{"data":[{"url":"www.example.com/abc1","views":1000,"relevanceScore":0.5}]}`,
+			expected: &types.UrlStatData{
+				Data: types.UrlStatSlice{{Url: "www.example.com/abc1", Views: 1000, RelevanceScore: 0.5}},
+			},
+		},
+		{
+			name:  "unknown fields allowed",
+			input: `{"data":[{"url":"www.example.com/abc1","clicks":1}]}`,
+			expected: &types.UrlStatData{
+				Data: types.UrlStatSlice{{Url: "www.example.com/abc1"}},
+			},
+		},
+		{
+			name:                       "unknown fields disallowed",
+			input:                      `{"data":[{"url":"www.example.com/abc1","clicks":1}]}`,
+			inputDisallowUnknownFields: true,
+			expectedErr:                true,
+		},
+		{
+			name:        "truncated payload",
+			input:       `{"data":[{"url":"www.example.com/abc1",`,
+			expectedErr: true,
+		},
+		{
+			name:        "html payload",
+			input:       `<html><body>Service Unavailable</body></html>`,
+			expectedErr: true,
+		},
+		{
+			name:        "trailing data",
+			input:       `{"data":[]} {"data":[]}`,
+			expectedErr: true,
+		},
+		{
+			name:        "wrong type",
+			input:       `{"data":[{"url":"www.example.com/abc1","views":"1000"}]}`,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result, resultErr := decodeUrlStatData(strings.NewReader(tc.input), tc.inputDisallowUnknownFields)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expected, result)
+			}
+			var invalidPayloadErr *invalidPayloadError
+			if errors.As(resultErr, &invalidPayloadErr) != tc.expectedErr {
+				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
+					tc.name, tc.expectedErr, resultErr)
+			}
+		})
+	}
+}
+
+func TestValidationConfigValidate(t *testing.T) {
+	const inputSource = "https://example.com/source.json"
+
+	validUrlStat := &types.UrlStat{Url: "www.example.com/abc1", Views: 1000, RelevanceScore: 0.5}
+	input := &types.UrlStatData{
+		Data: types.UrlStatSlice{
+			validUrlStat,
+			{Views: 1000, RelevanceScore: 0.5},
+			{Url: "www.example.com/abc3", Views: -1, RelevanceScore: 1.5},
+			nil,
+		},
+	}
+
+	testCases := []struct {
+		name               string
+		inputConfig        ValidationConfig
+		expectedData       *types.UrlStatData
+		expectedSkipped    int
+		expectedViolations []*types.Violation
+		expectedErr        bool
+	}{
+		{
+			name:            "default policy: skip",
+			expectedData:    &types.UrlStatData{Data: types.UrlStatSlice{validUrlStat}},
+			expectedSkipped: 3,
+		},
+		{
+			name:         "warn: invalid records are kept",
+			inputConfig:  ValidationConfig{Policy: ValidationWarn},
+			expectedData: input,
+		},
+		{
+			name:        "reject: source fails",
+			inputConfig: ValidationConfig{Policy: ValidationReject},
+			expectedErr: true,
+		},
+		{
+			name: "per source policy overrides the default",
+			inputConfig: ValidationConfig{
+				Policy:         ValidationReject,
+				SourcePolicies: map[string]ValidationPolicy{inputSource: ValidationWarn},
+			},
+			expectedData: input,
+		},
+	}
+
+	expectedViolations := []*types.Violation{
+		{Index: 1, Field: urlOption, Message: "is required"},
+		{Index: 2, Url: "www.example.com/abc3", Field: viewsOption, Message: "must not be negative. Got: -1"},
+		{Index: 2, Url: "www.example.com/abc3", Field: relevancescoreOption, Message: "must be between 0 and 1. Got: 1.5"},
+		{Index: 3, Message: "record is null"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result, report, resultErr := tc.inputConfig.validate(inputSource, input)
+			if !reflect.DeepEqual(result, tc.expectedData) {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expectedData, result)
+			}
+			if report.skipped != tc.expectedSkipped {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expectedSkipped, report.skipped)
+			}
+			if !reflect.DeepEqual(report.violations, expectedViolations) {
+				t.Fatalf("Test Failed: %v. Expected Result: %+v Actual Result: %+v",
+					tc.name, expectedViolations, report.violations)
+			}
+			assertErr := resultErr != nil
+			if assertErr != tc.expectedErr {
+				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
+					tc.name, tc.expectedErr, resultErr)
+			}
+		})
+	}
+}
+
+func TestValidationConfigFromEnv(t *testing.T) {
+	testCases := []struct {
+		name        string
+		inputEnv    map[string]string
+		expected    ValidationConfig
+		expectedErr bool
+	}{
+		{
+			name:     "no env: skip invalid records",
+			expected: ValidationConfig{Policy: ValidationSkip},
+		},
+		{
+			name: "default and per source policies",
+			inputEnv: map[string]string{
+				envVarValidationPolicy:                string(ValidationReject),
+				envVarValidationSourcePolicies:        "https://example.com/a.json?v=1=warn, google.json=skip",
+				envVarValidationDisallowUnknownFields: "true",
+			},
+			expected: ValidationConfig{
+				Policy: ValidationReject,
+				SourcePolicies: map[string]ValidationPolicy{
+					"https://example.com/a.json?v=1": ValidationWarn,
+					"google.json":                    ValidationSkip,
+				},
+				DisallowUnknownFields: true,
+			},
+		},
+		{
+			name: "unsupported policy",
+			inputEnv: map[string]string{
+				envVarValidationPolicy: "unsupported",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "unsupported per source policy",
+			inputEnv: map[string]string{
+				envVarValidationSourcePolicies: "google.json=unsupported",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "malformed per source policy",
+			inputEnv: map[string]string{
+				envVarValidationSourcePolicies: "google.json",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result, resultErr := ValidationConfigFromEnv(func(key string) string {
+				return tc.inputEnv[key]
+			})
+			assertErr := resultErr != nil
+			if assertErr != tc.expectedErr {
+				t.Fatalf("Test Failed: %v. Expected Error to occur: %v. Returned Error: %v",
+					tc.name, tc.expectedErr, resultErr)
+			}
+			if !tc.expectedErr && !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("Test Failed: %v. Expected Result: %+v Actual Result: %+v",
+					tc.name, tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestGetUrlStatsDataFromFile_Validation(t *testing.T) {
+	const (
+		testFolderDataSource = "testGetUrlStatsDataFromFile_Validation"
+
+		invalidRecordsFile = "invalid-records.json"
+		truncatedFile      = "truncated.json"
+		validFile          = "valid.json"
+	)
+
+	testCases := []struct {
+		name            string
+		inputConfig     ValidationConfig
+		expectedUrls    []string
+		expectedSources []*types.SourceStatus
+	}{
+		{
+			name:         "skip",
+			inputConfig:  ValidationConfig{Policy: ValidationSkip},
+			expectedUrls: []string{"www.example.com/abc2", "www.example.com/abc1"},
+			expectedSources: []*types.SourceStatus{
+				{Source: invalidRecordsFile, Success: true, Records: 1, Skipped: 2, ViolationCount: 3},
+				{Source: truncatedFile},
+				{Source: validFile, Success: true, Records: 1},
+			},
+		},
+		{
+			name:         "warn",
+			inputConfig:  ValidationConfig{Policy: ValidationWarn},
+			expectedUrls: []string{"www.example.com/abc2", "", "www.example.com/abc4", "www.example.com/abc1"},
+			expectedSources: []*types.SourceStatus{
+				{Source: invalidRecordsFile, Success: true, Records: 3, ViolationCount: 3},
+				{Source: truncatedFile},
+				{Source: validFile, Success: true, Records: 1},
+			},
+		},
+		{
+			name:         "reject",
+			inputConfig:  ValidationConfig{Policy: ValidationReject},
+			expectedUrls: []string{"www.example.com/abc1"},
+			expectedSources: []*types.SourceStatus{
+				{Source: invalidRecordsFile, ViolationCount: 3},
+				{Source: truncatedFile},
+				{Source: validFile, Success: true, Records: 1},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			urlStatService := urlStatDataService{
+				dataSourceType: urlDataSourceFile,
+				dataSourcePath: filepath.Join(serviceTestRelativePath, testFolderDataSource),
+				validation:     tc.inputConfig,
+			}
+			result, err := urlStatService.getUrlStatsDataFromFile(context.Background())
+			if err != nil {
+				t.Fatalf("Test Failed: %v. Unexpected Error: %v", tc.name, err)
+			}
+
+			var urls []string
+			for _, urlStat := range result.Data {
+				urls = append(urls, urlStat.Url)
+			}
+			if !reflect.DeepEqual(urls, tc.expectedUrls) {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, tc.expectedUrls, urls)
+			}
+
+			if len(result.Sources) != len(tc.expectedSources) {
+				t.Fatalf("Test Failed: %v. Expected Result: %v Actual Result: %v",
+					tc.name, len(tc.expectedSources), len(result.Sources))
+			}
+			for i, expected := range tc.expectedSources {
+				source := result.Sources[i]
+				if source.Source != expected.Source || source.Success != expected.Success ||
+					source.Records != expected.Records || source.Skipped != expected.Skipped ||
+					source.ViolationCount != expected.ViolationCount || len(source.Violations) != expected.ViolationCount {
+					t.Fatalf("Test Failed: %v. Expected Result: %+v Actual Result: %+v",
+						tc.name, expected, source)
+				}
+				if !source.Success && source.Error == "" {
+					t.Fatalf("Test Failed: %v. Expected an error for %v", tc.name, source.Source)
+				}
+			}
+		})
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/felipe88alves/sortKeyHttpServer
 
-go 1.19
+go 1.22
@@ -1,8 +1,11 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/felipe88alves/sortKeyHttpServer/api"
 )
@@ -13,15 +16,90 @@ const (
 )
 
 func main() {
+	loggingConfig, err := api.LoggingConfigFromEnv(os.Getenv)
+	if err != nil {
+		panic(err)
+	}
+	slog.SetDefault(api.NewLogger(os.Stderr, loggingConfig))
+
 	dataSourceType := os.Getenv(envVarUrlSource)
 	dataSourcePath := os.Getenv(envVarUrlPath)
 
-	svc, err := api.NewUrlStatDataService(dataSourceType, dataSourcePath)
+	dataConfig, err := api.DataConfigFromEnv(os.Getenv)
+	if err != nil {
+		panic(err)
+	}
+
+	retryPolicy, err := api.RetryPolicyFromEnv(os.Getenv)
+	if err != nil {
+		panic(err)
+	}
+
+	validation, err := api.ValidationConfigFromEnv(os.Getenv)
+	if err != nil {
+		panic(err)
+	}
+
+	mergeConfig, err := api.MergeConfigFromEnv(os.Getenv)
+	if err != nil {
+		panic(err)
+	}
+
+	normalizeConfig, err := api.NormalizeConfigFromEnv(os.Getenv)
 	if err != nil {
 		panic(err)
 	}
-	svc = api.NewLoggingService(svc)
 
-	apiServer := api.NewApiServer(svc)
-	log.Fatal(apiServer.Start(":5000"))
+	serverConfig, err := api.ServerConfigFromEnv(os.Getenv)
+	if err != nil {
+		panic(err)
+	}
+
+	compressionConfig, err := api.CompressionConfigFromEnv(os.Getenv)
+	if err != nil {
+		panic(err)
+	}
+
+	svc, err := api.NewUrlStatDataService(dataSourceType, dataSourcePath,
+		api.WithFetchTimeout(dataConfig.FetchTimeout),
+		api.WithAttemptTimeout(dataConfig.AttemptTimeout),
+		api.WithRetryPolicy(retryPolicy),
+		api.WithMaxConcurrency(dataConfig.MaxConcurrency, dataConfig.MaxConcurrencyPerHost),
+		api.WithHttpClient(api.NewHttpClient(dataConfig.MaxConcurrency, dataConfig.MaxConcurrencyPerHost)),
+		api.WithValidation(validation),
+		api.WithMergeConfig(mergeConfig),
+		api.WithNormalizeConfig(normalizeConfig),
+	)
+	if err != nil {
+		panic(err)
+	}
+	svc = api.NewLoggingService(svc, loggingConfig.PayloadSample)
+	svc = api.NewCachingService(context.Background(), svc, dataConfig.RefreshInterval)
+
+	apiServer := api.NewApiServer(svc,
+		api.WithMaxSnapshotAge(dataConfig.MaxSnapshotAge),
+		api.WithServerConfig(serverConfig),
+		api.WithCompression(compressionConfig),
+	)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv, serveErr, err := apiServer.Start(":5000")
+	if err != nil {
+		slog.Error("Failed to start server", "error", err)
+		os.Exit(1)
+	}
+	select {
+	case <-ctx.Done():
+	case err := <-serveErr:
+		slog.Error("Server stopped", "error", err)
+		os.Exit(1)
+	}
+	// A second signal terminates the process without waiting for the shutdown
+	stop()
+
+	if err := apiServer.Shutdown(context.Background(), srv); err != nil {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,14 @@
+package types
+
+// ErrorResponse follows the RFC 7807 Problem Details format.
+// Code, RequestId and ValidSortKeys are extension members.
+type ErrorResponse struct {
+	Type          string   `json:"type"`
+	Title         string   `json:"title"`
+	Status        int      `json:"status"`
+	Detail        string   `json:"detail"`
+	Instance      string   `json:"instance,omitempty"`
+	Code          string   `json:"code"`
+	RequestId     string   `json:"requestId,omitempty"`
+	ValidSortKeys []string `json:"validSortKeys,omitempty"`
+}
@@ -0,0 +1,13 @@
+package types
+
+type HealthStatus struct {
+	Status string `json:"status"`
+	// Reason explains why the service is not ready
+	Reason string `json:"reason,omitempty"`
+
+	Snapshot *Snapshot `json:"snapshot,omitempty"`
+	// LastError is the error of the last fetch cycle, if it failed
+	LastError string `json:"lastError,omitempty"`
+	// Sources reports the outcome of each source in the last fetch cycle
+	Sources []*SourceStatus `json:"sources,omitempty"`
+}
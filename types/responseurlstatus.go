@@ -3,4 +3,13 @@ package types
 type ResponseUrlStats struct {
 	SortedUrlStats *UrlStatSlice `json:"data"`
 	Count          int           `json:"count"`
+
+	// Total is the number of records across all pages
+	Total      int    `json:"total"`
+	HasMore    bool   `json:"hasMore"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+
+	Snapshot *Snapshot       `json:"snapshot,omitempty"`
+	Sources  []*SourceStatus `json:"sources,omitempty"`
 }
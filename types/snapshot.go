@@ -0,0 +1,10 @@
+package types
+
+import "time"
+
+type Snapshot struct {
+	Version     uint64    `json:"version"`
+	RefreshedAt time.Time `json:"refreshedAt"`
+	AgeSeconds  float64   `json:"ageSeconds"`
+	Stale       bool      `json:"stale"`
+}
@@ -0,0 +1,16 @@
+package types
+
+type SourceStatus struct {
+	Source     string  `json:"source"`
+	Success    bool    `json:"success"`
+	StatusCode int     `json:"statusCode,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	Records    int     `json:"records"`
+	Retries    int     `json:"retries"`
+	DurationMs float64 `json:"durationMs"`
+
+	// Skipped records failed validation and were dropped. Violations is capped, ViolationCount is not.
+	Skipped        int          `json:"skipped,omitempty"`
+	ViolationCount int          `json:"violationCount,omitempty"`
+	Violations     []*Violation `json:"violations,omitempty"`
+}
@@ -4,4 +4,10 @@ type UrlStat struct {
 	Url            string  `json:"url,omitempty"`
 	Views          int     `json:"views,omitempty"`
 	RelevanceScore float32 `json:"relevanceScore,omitempty"`
+
+	// NormalizedUrl is the canonical form of Url, used to merge and filter records
+	NormalizedUrl string `json:"normalizedUrl,omitempty"`
+
+	// Sources is only set on records merged from duplicates, listing the Data Sources they came from
+	Sources []string `json:"sources,omitempty"`
 }
@@ -2,4 +2,11 @@ package types
 
 type UrlStatData struct {
 	Data UrlStatSlice `json:"data,omitempty"`
+
+	// Snapshot is only set when the data is served from an in-memory cache
+	Snapshot *Snapshot `json:"-"`
+	// Sources reports the outcome of each HTTP Data Source Endpoint or file
+	Sources []*SourceStatus `json:"-"`
+	// SortedIndexes holds the records pre-sorted per sort key, only set by the in-memory cache
+	SortedIndexes map[string]UrlStatSlice `json:"-"`
 }
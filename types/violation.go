@@ -0,0 +1,9 @@
+package types
+
+// Violation describes a record that failed validation. Index is its position within the Data Source payload
+type Violation struct {
+	Index   int    `json:"index"`
+	Url     string `json:"url,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}